@@ -0,0 +1,34 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+)
+
+// Checksum is a SHA-256 over all embedded data (instruments, progressions and
+// every chord file), computed once at startup. It changes whenever the data does.
+var Checksum = computeChecksum()
+
+func computeChecksum() string {
+	h := sha256.New()
+	h.Write(InstrumentsJSON)
+	h.Write(ProgressionsJSON)
+	// WalkDir visits entries in lexical order, so the hash is deterministic.
+	err := fs.WalkDir(ChordsFS, "chords", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := ChordsFS.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		h.Write([]byte(path))
+		h.Write(b)
+		return nil
+	})
+	if err != nil {
+		panic("data: checksum: " + err.Error())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
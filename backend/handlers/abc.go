@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// abcSharps spells each pitch class for ABC notation (sharps, middle-C octave).
+var abcSharps = []string{"C", "^C", "D", "^D", "E", "F", "^F", "G", "^G", "A", "^A", "B"}
+
+// abcNote spells a MIDI note in ABC: 60 = "C", 72 = "c", 84 = "c'", 48 = "C,".
+func abcNote(n byte) string {
+	name := abcSharps[n%12]
+	octave := int(n)/12 - 1
+	if octave >= 5 {
+		name = strings.ToLower(name) + strings.Repeat("'", octave-5)
+	} else {
+		name += strings.Repeat(",", 4-octave)
+	}
+	return name
+}
+
+// abcLength writes a duration in beats against the L:1/4 unit length ("" for one beat).
+func abcLength(beats int) string {
+	if beats == 1 {
+		return ""
+	}
+	return strconv.Itoa(beats)
+}
+
+// buildABC renders the progression as an ABC chord chart: one block chord per
+// slot lasting its resolved duration (Beats, or the entry's "chord:beats"),
+// split and tied across bar lines of a Beats/4 measure.
+func buildABC(req MidiRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "X:1\nT:Chord progression\nM:%d/4\nL:1/4\nQ:1/4=%d\nK:C\n", req.Beats, req.Tempo)
+	headerLen := b.Len()
+
+	openMidi := tuning(req)
+	pos := 0 // beats into the current measure
+	for slot := 0; slot < totalBars(req); slot++ {
+		ci := slot % len(req.Chords)
+		notes, _, _ := slotNotes(req, openMidi, slot)
+		if len(notes) == 0 {
+			continue // unrecognised chord — skipped, as in the MIDI output
+		}
+		var chord strings.Builder
+		chord.WriteString("[")
+		for _, n := range notes {
+			chord.WriteString(abcNote(n))
+		}
+		chord.WriteString("]")
+
+		for left, first := beatsFor(req, ci), true; left > 0; first = false {
+			if pos == 0 && b.Len() > headerLen {
+				b.WriteString("| ")
+			}
+			if first {
+				fmt.Fprintf(&b, "%q", req.Chords[ci])
+			}
+			part := min(left, req.Beats-pos)
+			b.WriteString(chord.String() + abcLength(part))
+			if left -= part; left > 0 {
+				b.WriteString("-") // tie into the next measure
+			}
+			b.WriteString(" ")
+			pos = (pos + part) % req.Beats
+		}
+	}
+	b.WriteString("|]\n")
+	return b.String()
+}
+
+// ExportABC handles POST /api/export/abc. It takes the same body as
+// POST /api/midi and returns the progression as ABC notation.
+func ExportABC(c *gin.Context) {
+	req, ok := bindMidiRequest(c)
+	if !ok {
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=\"progression.abc\"")
+	c.Data(http.StatusOK, "text/vnd.abc; charset=utf-8", []byte(buildABC(req)))
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAbcNote(t *testing.T) {
+	cases := map[byte]string{60: "C", 61: "^C", 72: "c", 84: "c'", 48: "C,", 59: "B,"}
+	for n, want := range cases {
+		if got := abcNote(n); got != want {
+			t.Errorf("abcNote(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestBuildABC_Durations(t *testing.T) {
+	req := MidiRequest{
+		Chords:     []string{"C", "G", "Am", "F"},
+		chordBeats: []int{2, 2, 0, 3},
+		Tempo:      100,
+		Octave:     4,
+		Beats:      4,
+	}
+	got := buildABC(req)
+	// F's 3 beats start on beat 1 of bar 3 after Am's whole bar, so nothing ties.
+	want := `"C"[CEG]2 "G"[GBd]2 | "Am"[Ace]4 | "F"[FAc]3 |]`
+	if !strings.Contains(got, want) {
+		t.Errorf("buildABC body =\n%s\nwant it to contain\n%s", got, want)
+	}
+	if !strings.Contains(got, "M:4/4\n") || !strings.Contains(got, "Q:1/4=100\n") {
+		t.Errorf("buildABC header missing meter or tempo:\n%s", got)
+	}
+}
+
+func TestBuildABC_TiesAcrossBarLine(t *testing.T) {
+	req := MidiRequest{
+		Chords:     []string{"C", "G"},
+		chordBeats: []int{3, 0},
+		Octave:     4,
+		Beats:      4,
+	}
+	want := `"C"[CEG]3 "G"[GBd]- | [GBd]3 |]`
+	if got := buildABC(req); !strings.Contains(got, want) {
+		t.Errorf("buildABC =\n%s\nwant it to contain\n%s", got, want)
+	}
+}
+
+func TestExportABC_InlineHalfNote(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords": []string{"C:2", "G:2"},
+		"octave": 4,
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/export/abc", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/export/abc = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	// L:1/4, so a length of 2 is a half note.
+	if doc := w.Body.String(); !strings.Contains(doc, `"C"[CEG]2 `) {
+		t.Errorf("C:2 not rendered as a half note:\n%s", doc)
+	}
+}
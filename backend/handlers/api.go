@@ -3,9 +3,12 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 
@@ -13,6 +16,10 @@ import (
 	"guitartutor/backend/models"
 )
 
+// Version identifies the running build. Override at build time with
+// -ldflags "-X guitartutor/backend/handlers.Version=…".
+var Version = "dev"
+
 var chromatic = []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
 
 var flatToSharp = map[string]string{
@@ -51,42 +58,90 @@ func chordSuffix(chord string) string {
 	return chord[1:]
 }
 
-// transposeChord shifts a chord name by semitones.
+// transposeChord shifts a chord name by semitones. The bass note of a slash
+// chord moves with the root, so "C/E" up a fourth is "F/A".
 func transposeChord(chord string, semitones int) string {
 	idx := chordRootIndex(chord)
 	if idx == -1 {
 		return chord
 	}
 	newIdx := ((idx+semitones)%12 + 12) % 12
-	return chromatic[newIdx] + chordSuffix(chord)
+	suffix := chordSuffix(chord)
+	if i := strings.LastIndex(suffix, "/"); i != -1 {
+		if bass := suffix[i+1:]; chordRootIndex(bass) != -1 && chordSuffix(bass) == "" {
+			suffix = suffix[:i+1] + transposeChord(bass, semitones)
+		}
+	}
+	return chromatic[newIdx] + suffix
 }
 
 // getTransposition returns the number of semitones from fromKey to toKey.
+// Keys are normalised through flatToSharp, so "Gb" and "F#" are equivalent.
 func getTransposition(fromKey, toKey string) int {
-	from := -1
-	to := -1
-	for i, n := range chromatic {
-		if n == fromKey {
-			from = i
-		}
-		if n == toKey {
-			to = i
-		}
-	}
+	from := chordRootIndex(fromKey)
+	to := chordRootIndex(toKey)
 	if from == -1 || to == -1 {
 		return 0
 	}
 	return ((to - from) + 12) % 12
 }
 
+// normalizeChordName rewrites a flat root to its sharp spelling ("Bbm" → "A#m"),
+// matching the keys used in the chord diagram data.
+func normalizeChordName(chord string) string {
+	idx := chordRootIndex(chord)
+	if idx == -1 {
+		return chord
+	}
+	return chromatic[idx] + chordSuffix(chord)
+}
+
+// Parsed chord diagrams are immutable embedded data, so each instrument is
+// decoded once and shared. Callers must not modify the returned map.
+var (
+	diagramCacheMu sync.Mutex
+	diagramCache   = map[string]models.ChordDiagrams{}
+)
+
+// loadInstruments decodes the embedded instrument list.
+func loadInstruments() ([]models.Instrument, error) {
+	var instruments []models.Instrument
+	if err := json.Unmarshal(data.InstrumentsJSON, &instruments); err != nil {
+		return nil, err
+	}
+	return instruments, nil
+}
+
+// findInstrument returns the instrument with the given key.
+func findInstrument(key string) (models.Instrument, error) {
+	instruments, err := loadInstruments()
+	if err != nil {
+		return models.Instrument{}, err
+	}
+	for _, inst := range instruments {
+		if inst.Key == strings.ToLower(key) {
+			return inst, nil
+		}
+	}
+	return models.Instrument{}, fmt.Errorf("unknown instrument: %s", key)
+}
+
 // loadChordDiagrams reads the embedded JSON for the given instrument key.
 func loadChordDiagrams(instrument string) (models.ChordDiagrams, error) {
 	// Sanitise: only allow known instrument names.
 	allowed := map[string]bool{"guitar": true, "ukulele": true, "mandolin": true, "banjo": true, "piano": true}
-	if !allowed[strings.ToLower(instrument)] {
+	key := strings.ToLower(instrument)
+	if !allowed[key] {
 		return nil, fmt.Errorf("unknown instrument: %s", instrument)
 	}
-	path := fmt.Sprintf("chords/%s.json", strings.ToLower(instrument))
+
+	diagramCacheMu.Lock()
+	defer diagramCacheMu.Unlock()
+	if diagrams, ok := diagramCache[key]; ok {
+		return diagrams, nil
+	}
+
+	path := fmt.Sprintf("chords/%s.json", key)
 	b, err := data.ChordsFS.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("could not read chord data for %s: %w", instrument, err)
@@ -95,43 +150,237 @@ func loadChordDiagrams(instrument string) (models.ChordDiagrams, error) {
 	if err := json.Unmarshal(b, &diagrams); err != nil {
 		return nil, fmt.Errorf("could not parse chord data for %s: %w", instrument, err)
 	}
+	diagramCache[key] = diagrams
 	return diagrams, nil
 }
 
+// withFretStats returns copies of variants with SoundingStrings, LowestFret and
+// HighestFret filled in. The input comes from the shared diagram cache, so it
+// is never modified in place.
+func withFretStats(variants []models.ChordVariant) []models.ChordVariant {
+	out := make([]models.ChordVariant, len(variants))
+	for i, v := range variants {
+		if len(v.Frets) == 0 {
+			v.SoundingStrings = len(v.Keys)
+			out[i] = v
+			continue
+		}
+		for _, fv := range v.Frets {
+			if fv == "x" {
+				continue
+			}
+			v.SoundingStrings++
+			fret, err := strconv.Atoi(fv)
+			if err != nil || fret == 0 {
+				continue
+			}
+			if v.LowestFret == 0 || fret < v.LowestFret {
+				v.LowestFret = fret
+			}
+			if fret > v.HighestFret {
+				v.HighestFret = fret
+			}
+		}
+		out[i] = v
+	}
+	return out
+}
+
+// GetVersion returns the build version and a checksum of the embedded data,
+// so clients can tell when cached instruments/chords/progressions are stale.
+func GetVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, models.VersionResponse{
+		Version:      Version,
+		DataChecksum: data.Checksum,
+	})
+}
+
 // GetInstruments returns the list of supported instruments.
 func GetInstruments(c *gin.Context) {
-	var instruments []models.Instrument
-	if err := json.Unmarshal(data.InstrumentsJSON, &instruments); err != nil {
-		log.Printf("error loading instruments: %v", err)
+	instruments, err := loadInstruments()
+	if err != nil {
+		requestLogger(c).Error("could not load instruments", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load instruments"})
 		return
 	}
 	c.JSON(http.StatusOK, instruments)
 }
 
-// GetProgressions returns all chord progressions.
+// progressionLevels are the curated difficulty levels a progression can carry.
+var progressionLevels = map[string]bool{"beginner": true, "intermediate": true, "advanced": true}
+
+// GetProgressions returns all chord progressions, or with ?level= only those
+// tagged with that difficulty level.
 func GetProgressions(c *gin.Context) {
+	level := c.Query("level")
+	if level != "" && !progressionLevels[level] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "level must be beginner, intermediate or advanced"})
+		return
+	}
 	var progressions []models.Progression
 	if err := json.Unmarshal(data.ProgressionsJSON, &progressions); err != nil {
-		log.Printf("error loading progressions: %v", err)
+		requestLogger(c).Error("could not load progressions", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load progressions"})
 		return
 	}
+	if level != "" {
+		matching := []models.Progression{}
+		for _, p := range progressions {
+			if p.Level == level {
+				matching = append(matching, p)
+			}
+		}
+		progressions = matching
+	}
 	c.JSON(http.StatusOK, progressions)
 }
 
-// GetChords returns all chord diagrams for a single instrument.
+// findProgression returns the progression with the given name, ignoring case.
+// ok is false when there is none.
+func findProgression(name string) (p models.Progression, ok bool, err error) {
+	var progressions []models.Progression
+	if err := json.Unmarshal(data.ProgressionsJSON, &progressions); err != nil {
+		return models.Progression{}, false, err
+	}
+	for _, p := range progressions {
+		if strings.EqualFold(p.Name, name) {
+			return p, true, nil
+		}
+	}
+	return models.Progression{}, false, nil
+}
+
+// GetGenres returns the sorted, distinct genres tagged on the progressions.
+func GetGenres(c *gin.Context) {
+	var progressions []models.Progression
+	if err := json.Unmarshal(data.ProgressionsJSON, &progressions); err != nil {
+		requestLogger(c).Error("could not load progressions", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load progressions"})
+		return
+	}
+	genres := []string{}
+	seen := map[string]bool{}
+	for _, p := range progressions {
+		if p.Genre != "" && !seen[p.Genre] {
+			seen[p.Genre] = true
+			genres = append(genres, p.Genre)
+		}
+	}
+	sort.Strings(genres)
+	c.JSON(http.StatusOK, genres)
+}
+
+// altTunings holds open-string MIDI notes for tunings other than "standard",
+// which comes from the instrument data.
+var altTunings = map[string]map[string][]int{
+	"guitar": {
+		"drop-d":         {38, 45, 50, 55, 59, 64},
+		"half-step-down": {39, 44, 49, 54, 58, 63},
+		"dadgad":         {38, 45, 50, 55, 57, 62},
+		"open-g":         {38, 43, 50, 55, 59, 62},
+		"open-d":         {38, 45, 50, 54, 57, 62},
+	},
+	"banjo": {
+		"double-c": {67, 48, 55, 60, 62},
+	},
+}
+
+// GetOpenStrings handles GET /api/open-strings/:instrument?tuning=standard,
+// returning the open-string MIDI notes a client sends as openMidi.
+func GetOpenStrings(c *gin.Context) {
+	inst, err := findInstrument(c.Param("instrument"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(inst.OpenMidi) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": inst.Name + " has no open strings"})
+		return
+	}
+	tuning := c.DefaultQuery("tuning", "standard")
+	openMidi := inst.OpenMidi
+	if tuning != "standard" {
+		var ok bool
+		if openMidi, ok = altTunings[inst.Key][tuning]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown %s tuning: %s", inst.Key, tuning)})
+			return
+		}
+	}
+	notes := make([]string, len(openMidi))
+	for i, m := range openMidi {
+		notes[i] = midiNoteName(byte(m))
+	}
+	c.JSON(http.StatusOK, models.OpenStringsResponse{
+		Instrument: inst.Key,
+		Tuning:     tuning,
+		OpenMidi:   openMidi,
+		Notes:      notes,
+	})
+}
+
+// GetChords returns all chord diagrams for a single instrument. With
+// ?variants=primary only each chord's first (open/easiest) variant is returned.
 func GetChords(c *gin.Context) {
 	instrument := c.Param("instrument")
+	mode := c.DefaultQuery("variants", "all")
+	if mode != "all" && mode != "primary" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "variants must be all or primary"})
+		return
+	}
 	diagrams, err := loadChordDiagrams(instrument)
 	if err != nil {
+		requestLogger(c).Warn("chord lookup failed", "instrument", instrument, "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, diagrams)
+	resp := make(models.ChordDiagrams, len(diagrams))
+	for chord, variants := range diagrams {
+		if mode == "primary" && len(variants) > 1 {
+			variants = variants[:1]
+		}
+		resp[chord] = withFretStats(variants)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetChordInstruments returns the instruments that have at least one diagram
+// for a chord. Sharps must be URL-encoded ("C%23m"); flat names are accepted too.
+//
+// gin requires one wildcard name per path segment, so the chord arrives in the
+// "instrument" param shared with GET /api/chords/:instrument.
+func GetChordInstruments(c *gin.Context) {
+	chord := normalizeChordName(c.Param("instrument"))
+	if chordRootIndex(chord) == -1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unrecognised chord: " + c.Param("instrument")})
+		return
+	}
+
+	instruments, err := loadInstruments()
+	if err != nil {
+		requestLogger(c).Error("could not load instruments", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load instruments"})
+		return
+	}
+
+	playable := []string{}
+	for _, inst := range instruments {
+		diagrams, err := loadChordDiagrams(inst.Key)
+		if err != nil {
+			requestLogger(c).Error("could not load chord data", "instrument", inst.Key, "error", err)
+			continue
+		}
+		if len(diagrams[chord]) > 0 {
+			playable = append(playable, inst.Key)
+		}
+	}
+	c.JSON(http.StatusOK, models.ChordInstrumentsResponse{
+		Chord:       chord,
+		Instruments: playable,
+	})
 }
 
-// BatchChords returns chord diagrams for a requested subset of chord names on one instrument.
+// BatchChords returns chord diagrams for a requested subset of chord names on one
+// instrument, as a map keyed by chord name or, with ?ordered=true, an array.
 func BatchChords(c *gin.Context) {
 	var req models.BatchChordsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -141,21 +390,261 @@ func BatchChords(c *gin.Context) {
 
 	diagrams, err := loadChordDiagrams(req.Instrument)
 	if err != nil {
+		requestLogger(c).Warn("batch chord lookup failed", "instrument", req.Instrument, "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// ?ordered=true returns an array in request order, duplicates included;
+	// the default map form is kept for existing clients.
+	if c.Query("ordered") == "true" {
+		ordered := make([]models.OrderedChordVariants, len(req.Chords))
+		for i, chord := range req.Chords {
+			ordered[i] = models.OrderedChordVariants{
+				Chord:    chord,
+				Variants: withFretStats(diagrams[chord]),
+			}
+		}
+		requestLogger(c).Info("batch chords served", "instrument", req.Instrument, "chords", len(req.Chords), "ordered", true)
+		c.JSON(http.StatusOK, ordered)
+		return
+	}
+
 	resp := make(models.BatchChordsResponse)
 	for _, chord := range req.Chords {
 		if variants, ok := diagrams[chord]; ok {
-			resp[chord] = variants
+			resp[chord] = withFretStats(variants)
 		} else {
 			resp[chord] = []models.ChordVariant{}
 		}
 	}
+	requestLogger(c).Info("batch chords served", "instrument", req.Instrument, "chords", len(req.Chords))
+	c.JSON(http.StatusOK, resp)
+}
+
+// PositionVoicings handles POST /api/position-voicings, returning, in request
+// order, each chord's variants whose fretted notes all lie in minFret–maxFret.
+func PositionVoicings(c *gin.Context) {
+	var req models.PositionVoicingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.MinFret < 0 || req.MaxFret > maxFret || req.MinFret > req.MaxFret {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("minFret–maxFret must be a window within 0–%d", maxFret)})
+		return
+	}
+	inst, err := findInstrument(req.Instrument)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if inst.DisplayType == "keyboard" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": inst.Key + " has no frets"})
+		return
+	}
+	diagrams, err := loadChordDiagrams(req.Instrument)
+	if err != nil {
+		requestLogger(c).Warn("position voicing lookup failed", "instrument", req.Instrument, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := make([]models.OrderedChordVariants, len(req.Chords))
+	for i, chord := range req.Chords {
+		variants := []models.ChordVariant{}
+		for _, v := range withFretStats(diagrams[normalizeChordName(chord)]) {
+			// Open strings need no hand, so only fretted notes must fit.
+			if v.HighestFret == 0 || (v.LowestFret >= req.MinFret && v.HighestFret <= req.MaxFret) {
+				variants = append(variants, v)
+			}
+		}
+		resp[i] = models.OrderedChordVariants{Chord: chord, Variants: variants}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// variantMidi returns the sorted MIDI notes a chord variant sounds: frets on
+// the instrument's open strings, or the key names of a piano voicing.
+func variantMidi(inst models.Instrument, v models.ChordVariant) []int {
+	var notes []int
+	if len(v.Frets) > 0 && len(inst.OpenMidi) > 0 {
+		for _, n := range fretsToMidi(v.Frets, inst.OpenMidi) {
+			notes = append(notes, int(n))
+		}
+		return notes
+	}
+	for _, n := range keysToMidi(v.Keys) {
+		notes = append(notes, int(n))
+	}
+	return notes
+}
+
+// CompareChords handles POST /api/chords/compare, listing the notes two
+// voicings share and the ones unique to each.
+func CompareChords(c *gin.Context) {
+	var req models.CompareChordsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	inst, err := findInstrument(req.Instrument)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	diagrams, err := loadChordDiagrams(inst.Key)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var notes [2][]int
+	for i, pick := range []struct {
+		chord   string
+		variant int
+	}{{req.ChordA, req.VariantA}, {req.ChordB, req.VariantB}} {
+		variants := diagrams[normalizeChordName(pick.chord)]
+		if len(variants) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no %s diagram for chord: %s", inst.Key, pick.chord)})
+			return
+		}
+		if pick.variant < 0 || pick.variant >= len(variants) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s: variant must be in range 0–%d", pick.chord, len(variants)-1)})
+			return
+		}
+		notes[i] = variantMidi(inst, variants[pick.variant])
+	}
+
+	var inA, inB [12]bool
+	for _, n := range notes[0] {
+		inA[n%12] = true
+	}
+	for _, n := range notes[1] {
+		inB[n%12] = true
+	}
+	resp := models.CompareChordsResponse{
+		NotesA: notes[0],
+		NotesB: notes[1],
+		Common: []string{},
+		OnlyA:  []string{},
+		OnlyB:  []string{},
+	}
+	for pc, name := range chromatic {
+		switch {
+		case inA[pc] && inB[pc]:
+			resp.Common = append(resp.Common, name)
+		case inA[pc]:
+			resp.OnlyA = append(resp.OnlyA, name)
+		case inB[pc]:
+			resp.OnlyB = append(resp.OnlyB, name)
+		}
+	}
 	c.JSON(http.StatusOK, resp)
 }
 
+// maxFret is the highest fret a shifted shape may use.
+const maxFret = 24
+
+// shiftFrets moves a fret shape by semitones. Open strings move with the shape,
+// since the nut doesn't; any string that lands below fret 0 or above maxFret is
+// muted and its index reported. Muted strings stay muted.
+func shiftFrets(frets []string, semitones int) ([]string, []int, error) {
+	shifted := make([]string, len(frets))
+	unplayable := []int{}
+	for i, fv := range frets {
+		if fv == "x" {
+			shifted[i] = "x"
+			continue
+		}
+		fret, err := strconv.Atoi(fv)
+		if err != nil {
+			return nil, nil, fmt.Errorf("frets[%d]: invalid fret %q", i, fv)
+		}
+		fret += semitones
+		if fret < 0 || fret > maxFret {
+			shifted[i] = "x"
+			unplayable = append(unplayable, i)
+			continue
+		}
+		shifted[i] = strconv.Itoa(fret)
+	}
+	return shifted, unplayable, nil
+}
+
+// ShiftFrets handles POST /api/shift-frets, moving a movable shape along the neck.
+func ShiftFrets(c *gin.Context) {
+	var req models.ShiftFretsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Semitones < -maxFret || req.Semitones > maxFret {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("semitones must be in range -%d–%d", maxFret, maxFret)})
+		return
+	}
+	frets, unplayable, err := shiftFrets(req.Frets, req.Semitones)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.ShiftFretsResponse{Frets: frets, Unplayable: unplayable})
+}
+
+// rangeEdgeSpan is how close to either end of an instrument's range a
+// voicing may sit before transposeWarnings flags it: reaching into the top
+// octave is very high, fitting entirely inside the bottom octave very low.
+const rangeEdgeSpan = 12
+
+// transposeWarnings reports the transposed chords whose primary voicing on
+// inst, raised by any capo, sits at the edge of the instrument's range: a top
+// note within rangeEdgeSpan of MaxMidi, or every note within rangeEdgeSpan of
+// MinMidi. Chords without a diagram are skipped.
+func transposeWarnings(inst models.Instrument, chords []string, semitones, capo int) []string {
+	diagrams, err := loadChordDiagrams(inst.Key)
+	if err != nil || inst.MaxMidi == 0 {
+		return nil
+	}
+	var high, low []string
+	top, bottom := 0, 127
+	for _, ch := range chords {
+		moved := transposeChord(ch, semitones)
+		variants := diagrams[normalizeChordName(moved)]
+		if len(variants) == 0 {
+			continue
+		}
+		notes := variantMidi(inst, variants[0])
+		if len(notes) == 0 {
+			continue
+		}
+		if n := slices.Max(notes) + capo; n > inst.MaxMidi-rangeEdgeSpan {
+			high = append(high, moved)
+			top = max(top, n)
+		} else if n < inst.MinMidi+rangeEdgeSpan {
+			low = append(low, moved)
+			bottom = min(bottom, slices.Min(notes)+capo)
+		}
+	}
+	var warnings []string
+	if len(high) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%s %s very high on the %s (up to %s); consider a capo with lower shapes or dropping an octave",
+			strings.Join(high, ", "), sitOrSits(high), inst.Name, midiNoteName(byte(min(top, 127)))))
+	}
+	if len(low) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%s %s very low on the %s (down to %s); consider a capo or higher shapes",
+			strings.Join(low, ", "), sitOrSits(low), inst.Name, midiNoteName(byte(bottom))))
+	}
+	return warnings
+}
+
+// sitOrSits agrees the verb of a range warning with the chords it lists.
+func sitOrSits(chords []string) string {
+	if len(chords) == 1 {
+		return "sits"
+	}
+	return "sit"
+}
+
 // Transpose performs a batch transposition of chord names from one key to another.
 func Transpose(c *gin.Context) {
 	var req models.TransposeRequest
@@ -163,8 +652,35 @@ func Transpose(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.Capo < 0 || req.Capo > 12 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "capo must be in range 0–12"})
+		return
+	}
+	format := c.DefaultQuery("format", "full")
+	if format != "full" && format != "names" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be full or names"})
+		return
+	}
+
+	var inst models.Instrument
+	if req.Instrument != "" {
+		var err error
+		if inst, err = findInstrument(req.Instrument); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	semitones := getTransposition(req.FromKey, req.ToKey)
+	// ?format=names returns just the transposed chord names, for light clients.
+	if format == "names" {
+		names := make([]string, len(req.Chords))
+		for i, ch := range req.Chords {
+			names[i] = transposeChord(ch, semitones)
+		}
+		c.JSON(http.StatusOK, names)
+		return
+	}
 	results := make([]models.TransposedChord, len(req.Chords))
 	for i, ch := range req.Chords {
 		results[i] = models.TransposedChord{
@@ -172,8 +688,182 @@ func Transpose(c *gin.Context) {
 			Transposed: transposeChord(ch, semitones),
 		}
 	}
-	c.JSON(http.StatusOK, models.TransposeResponse{
+	resp := models.TransposeResponse{
 		Semitones: semitones,
 		Results:   results,
-	})
+	}
+	if req.Capo > 0 {
+		// The shapes are fingered in to_key; the capo raises what actually sounds.
+		resp.SoundingKey = transposeChord(req.ToKey, req.Capo)
+	}
+	if inst.Key != "" {
+		resp.Warnings = transposeWarnings(inst, req.Chords, semitones, req.Capo)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// TransposeProgression handles POST /api/progressions/:name/transpose,
+// returning the curated progression moved to to_key as a new Progression with
+// its chords and OriginalKey rewritten and everything else unchanged.
+func TransposeProgression(c *gin.Context) {
+	var req models.ProgressionTransposeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if chordRootIndex(req.ToKey) == -1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown key: " + req.ToKey})
+		return
+	}
+	prog, ok, err := findProgression(c.Param("name"))
+	if err != nil {
+		requestLogger(c).Error("could not load progressions", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load progressions"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown progression: " + c.Param("name")})
+		return
+	}
+
+	semitones := getTransposition(prog.OriginalKey, req.ToKey)
+	chords := make([]string, len(prog.Chords))
+	for i, ch := range prog.Chords {
+		chords[i] = transposeChord(ch, semitones)
+	}
+	prog.Chords = chords
+	prog.OriginalKey = transposeChord(prog.OriginalKey, semitones)
+	c.JSON(http.StatusOK, prog)
+}
+
+// isBarre reports whether a fretted variant has one finger holding down two
+// or more strings.
+func isBarre(v models.ChordVariant) bool {
+	seen := map[string]bool{}
+	for _, f := range v.Fingers {
+		if f == "" {
+			continue
+		}
+		if seen[f] {
+			return true
+		}
+		seen[f] = true
+	}
+	return false
+}
+
+// variantDifficulty rates a fretted variant from 1 (open chord) to 5: a barre
+// adds two, a shape with no open strings one, and sitting at the fifth fret
+// or above one more. The variant must have its fret stats filled in.
+func variantDifficulty(v models.ChordVariant) int {
+	d := 1
+	if isBarre(v) {
+		d += 2
+	}
+	if !slices.Contains(v.Frets, "0") {
+		d++
+	}
+	if v.LowestFret >= 5 {
+		d++
+	}
+	return d
+}
+
+// TransposeDiff handles POST /api/transpose/diff, comparing each chord's
+// primary diagram with its transposed counterpart to gauge how much of the
+// song must be relearned in the new key.
+func TransposeDiff(c *gin.Context) {
+	var req models.TransposeDiffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	inst, err := findInstrument(req.Instrument)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if inst.DisplayType == "keyboard" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": inst.Key + " has no chord shapes to compare"})
+		return
+	}
+	diagrams, err := loadChordDiagrams(inst.Key)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// difficulty rates a chord's primary diagram, and is 0 without one.
+	difficulty := func(chord string) (int, bool) {
+		variants := withFretStats(diagrams[normalizeChordName(chord)])
+		if len(variants) == 0 {
+			return 0, false
+		}
+		return variantDifficulty(variants[0]), isBarre(variants[0])
+	}
+
+	semitones := getTransposition(req.FromKey, req.ToKey)
+	resp := models.TransposeDiffResponse{Semitones: semitones, Chords: make([]models.ChordShapeChange, len(req.Chords))}
+	for i, ch := range req.Chords {
+		change := models.ChordShapeChange{Original: ch, Transposed: transposeChord(ch, semitones)}
+		var fromBarre, toBarre bool
+		change.OriginalDifficulty, fromBarre = difficulty(change.Original)
+		change.TransposedDifficulty, toBarre = difficulty(change.Transposed)
+		switch {
+		case change.TransposedDifficulty == 0:
+			change.Reason = "no " + inst.Key + " diagram for " + change.Transposed
+		case toBarre && !fromBarre:
+			change.Reason = "becomes a barre chord"
+		case change.TransposedDifficulty > change.OriginalDifficulty:
+			change.Reason = "harder shape"
+		}
+		if change.Reason != "" {
+			change.Changed = true
+			resp.Changed++
+		}
+		resp.Chords[i] = change
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// LearnOrder handles POST /api/learn-order, listing a song's distinct chords
+// from easiest to hardest primary diagram so a teacher can introduce them one
+// at a time. Equally hard chords keep the order they first appear in.
+func LearnOrder(c *gin.Context) {
+	var req models.LearnOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	inst, err := findInstrument(req.Instrument)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if inst.DisplayType == "keyboard" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": inst.Key + " has no chord shapes to rate"})
+		return
+	}
+	diagrams, err := loadChordDiagrams(inst.Key)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := models.LearnOrderResponse{Chords: []models.LearnOrderChord{}, Missing: []string{}}
+	seen := map[string]bool{}
+	for _, ch := range req.Chords {
+		name := normalizeChordName(ch)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		variants := withFretStats(diagrams[name])
+		if len(variants) == 0 {
+			resp.Missing = append(resp.Missing, ch)
+			continue
+		}
+		resp.Chords = append(resp.Chords, models.LearnOrderChord{Chord: ch, Difficulty: variantDifficulty(variants[0])})
+	}
+	slices.SortStableFunc(resp.Chords, func(a, b models.LearnOrderChord) int { return a.Difficulty - b.Difficulty })
+	c.JSON(http.StatusOK, resp)
 }
@@ -5,9 +5,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+
+	"guitartutor/backend/models"
 )
 
 func init() {
@@ -16,14 +21,101 @@ func init() {
 
 func newRouter() *gin.Engine {
 	r := gin.New()
+	r.Use(RequestID())
+	r.Use(Gzip())
+	r.GET("/api/version", GetVersion)
 	r.GET("/api/instruments", GetInstruments)
+	r.GET("/api/open-strings/:instrument", GetOpenStrings)
 	r.GET("/api/progressions", GetProgressions)
+	r.GET("/api/progressions/:name/midi", GetProgressionMidi)
+	r.POST("/api/progressions/:name/transpose", TransposeProgression)
+	r.GET("/api/genres", GetGenres)
 	r.POST("/api/transpose", Transpose)
+	r.POST("/api/transpose/diff", TransposeDiff)
+	r.POST("/api/learn-order", LearnOrder)
+	r.POST("/api/shift-frets", ShiftFrets)
+	r.POST("/api/substitute", Substitute)
+	r.POST("/api/simplify", Simplify)
+	r.POST("/api/add-sevenths", AddSevenths)
+	r.POST("/api/parallel", Parallel)
+	r.POST("/api/solo-guide", SoloGuide)
+	r.GET("/api/pivot", GetPivotChords)
+	r.GET("/api/chord-formula/:chord", GetChordFormula)
+	r.GET("/api/caged/:chord", GetCagedShapes)
+	r.POST("/api/turnaround", Turnaround)
+	r.POST("/api/reharmonize", Reharmonize)
+	r.POST("/api/infer-key", InferKey)
+	r.GET("/api/chords/:instrument", GetChords)
+	r.GET("/api/chords/:instrument/instruments", GetChordInstruments)
+	r.GET("/api/chords/:instrument/:chord/midi", GetChordMidi)
+	r.GET("/api/chords/:instrument/:chord/midi-all", GetChordMidiAll)
 	r.POST("/api/chords/batch", BatchChords)
+	r.POST("/api/position-voicings", PositionVoicings)
+	r.POST("/api/chords/compare", CompareChords)
 	r.POST("/api/midi", GenerateMidi)
+	r.POST("/api/midi/events", GetMidiEvents)
+	r.POST("/api/quantize", QuantizeEvents)
+	r.POST("/api/export/abc", ExportABC)
+	r.POST("/api/song", GenerateSong)
+	r.POST("/api/tempo-ladder", GenerateTempoLadder)
+	r.POST("/api/interval-midi", GenerateIntervalMidi)
+	r.POST("/api/validate-diagrams", ValidateDiagrams)
 	return r
 }
 
+// ── request IDs ───────────────────────────────────────────────────────────
+
+func TestRequestID_HeaderSet(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/instruments", nil)
+	r.ServeHTTP(w, req)
+
+	if id := w.Header().Get(RequestIDHeader); id == "" {
+		t.Errorf("response missing %s header", RequestIDHeader)
+	}
+}
+
+func TestRequestID_EchoesClientID(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/instruments", nil)
+	req.Header.Set(RequestIDHeader, "abc123")
+	r.ServeHTTP(w, req)
+
+	if id := w.Header().Get(RequestIDHeader); id != "abc123" {
+		t.Errorf("%s = %q, want abc123", RequestIDHeader, id)
+	}
+}
+
+// ── /api/version ──────────────────────────────────────────────────────────
+
+func TestGetVersion_StableChecksum(t *testing.T) {
+	var checksums []string
+	for i := 0; i < 2; i++ {
+		r := newRouter()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/version", nil)
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /api/version = %d, want 200", w.Code)
+		}
+		var resp map[string]string
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp["version"] == "" {
+			t.Error("version is empty")
+		}
+		checksums = append(checksums, resp["dataChecksum"])
+	}
+	if checksums[0] == "" {
+		t.Fatal("dataChecksum is empty")
+	}
+	if checksums[0] != checksums[1] {
+		t.Errorf("dataChecksum changed between calls: %s vs %s", checksums[0], checksums[1])
+	}
+}
+
 // ── /api/instruments ──────────────────────────────────────────────────────
 
 func TestGetInstruments(t *testing.T) {
@@ -70,6 +162,129 @@ func TestGetProgressions(t *testing.T) {
 	}
 }
 
+func TestGetProgressionMidi(t *testing.T) {
+	name := url.PathEscape("I-V-vi-IV (Pop Progression)")
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/progressions/"+name+"/midi?pattern=pop-strum&tempo=100&instrument=guitar", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET progression midi = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	if midi := w.Body.Bytes(); len(midi) < 4 || string(midi[0:4]) != "MThd" {
+		t.Errorf("response is not a valid MIDI file")
+	}
+
+	cases := map[string]int{
+		"/api/progressions/No%20Such%20Thing/midi":        http.StatusNotFound,
+		"/api/progressions/" + name + "/midi?pattern=bad": http.StatusBadRequest,
+		"/api/progressions/" + name + "/midi?tempo=0":     http.StatusBadRequest,
+	}
+	for path, want := range cases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("GET %s = %d, want %d", path, w.Code, want)
+		}
+	}
+}
+
+func TestTransposeProgression(t *testing.T) {
+	name := url.PathEscape("I-V-vi-IV (Pop Progression)")
+	body, _ := json.Marshal(map[string]string{"to_key": "G"})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/progressions/"+name+"/transpose", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST progression transpose = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var prog models.Progression
+	if err := json.Unmarshal(w.Body.Bytes(), &prog); err != nil {
+		t.Fatalf("could not decode progression: %v", err)
+	}
+	if want := []string{"G", "D", "Em", "C"}; !reflect.DeepEqual(prog.Chords, want) {
+		t.Errorf("chords = %v, want %v", prog.Chords, want)
+	}
+	if prog.OriginalKey != "G" {
+		t.Errorf("originalKey = %q, want G", prog.OriginalKey)
+	}
+	if prog.Name != "I-V-vi-IV (Pop Progression)" || len(prog.Songs) == 0 {
+		t.Errorf("name %q and %d songs should carry over", prog.Name, len(prog.Songs))
+	}
+
+	cases := map[string]struct {
+		key  string
+		want int
+	}{
+		"/api/progressions/No%20Such%20Thing/transpose": {"G", http.StatusNotFound},
+		"/api/progressions/" + name + "/transpose":      {"H", http.StatusBadRequest},
+	}
+	for path, tc := range cases {
+		body, _ := json.Marshal(map[string]string{"to_key": tc.key})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("POST %s to %s = %d, want %d", path, tc.key, w.Code, tc.want)
+		}
+	}
+}
+
+func TestGetProgressions_LevelFilter(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/progressions?level=beginner", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/progressions?level=beginner = %d, want 200", w.Code)
+	}
+	var progressions []models.Progression
+	json.Unmarshal(w.Body.Bytes(), &progressions)
+	if len(progressions) == 0 {
+		t.Fatal("no beginner progressions")
+	}
+	for _, p := range progressions {
+		if p.Level != "beginner" {
+			t.Errorf("%s has level %q, want beginner", p.Name, p.Level)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/progressions?level=expert", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown level = %d, want 400", w.Code)
+	}
+}
+
+func TestGetGenres_SortedDistinct(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/genres", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/genres = %d, want 200", w.Code)
+	}
+	var genres []string
+	json.Unmarshal(w.Body.Bytes(), &genres)
+	if len(genres) == 0 {
+		t.Fatal("no genres returned")
+	}
+	for i := 1; i < len(genres); i++ {
+		if genres[i-1] >= genres[i] {
+			t.Errorf("genres not sorted and distinct: %q before %q", genres[i-1], genres[i])
+		}
+	}
+}
+
 // ── /api/transpose ────────────────────────────────────────────────────────
 
 func TestTranspose_CtoG(t *testing.T) {
@@ -102,6 +317,111 @@ func TestTranspose_CtoG(t *testing.T) {
 	}
 }
 
+func TestTranspose_NamesFormat(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"from_key": "C",
+		"to_key":   "G",
+		"chords":   []string{"C", "Am", "F", "G"},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/transpose?format=names", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/transpose?format=names = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var names []string
+	if err := json.Unmarshal(w.Body.Bytes(), &names); err != nil {
+		t.Fatalf("response is not a string array: %v", err)
+	}
+	if want := []string{"G", "Em", "C", "D"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/transpose?format=xml", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("format=xml = %d, want 400", w.Code)
+	}
+}
+
+func TestTransposeDiff_OpenToBarreKey(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"from_key":   "G",
+		"to_key":     "F#",
+		"chords":     []string{"G", "C", "D", "Em"},
+		"instrument": "guitar",
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/transpose/diff", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/transpose/diff = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp models.TransposeDiffResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if resp.Changed != 4 {
+		t.Errorf("changed = %d, want all 4 open chords flagged: %+v", resp.Changed, resp.Chords)
+	}
+	for _, ch := range resp.Chords {
+		if ch.OriginalDifficulty != 1 || ch.TransposedDifficulty <= 1 || ch.Reason == "" {
+			t.Errorf("%s → %s = %+v, want an open chord turned harder", ch.Original, ch.Transposed, ch)
+		}
+	}
+
+	// Same key: nothing to relearn.
+	body, _ = json.Marshal(map[string]interface{}{
+		"from_key": "G", "to_key": "G", "chords": []string{"G", "C", "D"}, "instrument": "guitar",
+	})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/transpose/diff", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Changed != 0 {
+		t.Errorf("same-key changed = %d, want 0", resp.Changed)
+	}
+}
+
+func TestLearnOrder_OpenChordsFirst(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"instrument": "guitar",
+		"chords":     []string{"F#", "G", "Bm", "C", "G", "D", "Hx7"},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/learn-order", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/learn-order = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp models.LearnOrderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	var order []string
+	for _, ch := range resp.Chords {
+		order = append(order, ch.Chord)
+	}
+	if want := []string{"G", "C", "D", "F#", "Bm"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want open chords first then barres: %+v", order, resp.Chords)
+	}
+	if !reflect.DeepEqual(resp.Missing, []string{"Hx7"}) {
+		t.Errorf("missing = %v, want [Hx7]", resp.Missing)
+	}
+}
+
 func TestTranspose_SameKey(t *testing.T) {
 	body, _ := json.Marshal(map[string]interface{}{
 		"from_key": "C",
@@ -124,129 +444,1245 @@ func TestTranspose_SameKey(t *testing.T) {
 	}
 }
 
-// ── /api/chords/batch ─────────────────────────────────────────────────────
-
-func TestBatchChords_Guitar(t *testing.T) {
+func TestTranspose_CapoSoundingKey(t *testing.T) {
 	body, _ := json.Marshal(map[string]interface{}{
-		"instrument": "guitar",
-		"chords":     []string{"C", "G", "Am"},
+		"from_key": "A",
+		"to_key":   "G",
+		"chords":   []string{"A", "D", "E"},
+		"capo":     2,
 	})
 	r := newRouter()
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/api/chords/batch", bytes.NewReader(body))
+	req, _ := http.NewRequest("POST", "/api/transpose", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	r.ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
-		t.Fatalf("POST /api/chords/batch = %d, want 200; body: %s", w.Code, w.Body)
+		t.Fatalf("POST /api/transpose = %d, want 200; body: %s", w.Code, w.Body)
 	}
 	var resp map[string]interface{}
 	json.Unmarshal(w.Body.Bytes(), &resp)
-	if _, ok := resp["C"]; !ok {
-		t.Error("response missing 'C' key")
+	if resp["sounding_key"] != "A" {
+		t.Errorf("sounding_key = %v, want A", resp["sounding_key"])
 	}
 }
 
-func TestBatchChords_UnknownInstrument(t *testing.T) {
+func TestTranspose_NoCapoOmitsSoundingKey(t *testing.T) {
 	body, _ := json.Marshal(map[string]interface{}{
-		"instrument": "kazoo",
-		"chords":     []string{"C"},
+		"from_key": "C",
+		"to_key":   "G",
+		"chords":   []string{"C"},
 	})
 	r := newRouter()
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/api/chords/batch", bytes.NewReader(body))
+	req, _ := http.NewRequest("POST", "/api/transpose", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	r.ServeHTTP(w, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("unknown instrument should return 400, got %d", w.Code)
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if _, ok := resp["sounding_key"]; ok {
+		t.Error("sounding_key should be omitted when no capo is given")
 	}
 }
 
-// ── /api/midi ─────────────────────────────────────────────────────────────
-
-func TestGenerateMidi_Basic(t *testing.T) {
-	body, _ := json.Marshal(map[string]interface{}{
-		"chords":  []string{"C", "G"},
-		"tempo":   120,
-		"pattern": "quarter",
-		"octave":  4,
-		"beats":   4,
-	})
+func postTranspose(t *testing.T, fields map[string]interface{}) models.TransposeResponse {
+	t.Helper()
+	body, _ := json.Marshal(fields)
 	r := newRouter()
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req, _ := http.NewRequest("POST", "/api/transpose", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	r.ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
-		t.Fatalf("POST /api/midi = %d, want 200; body: %s", w.Code, w.Body)
+		t.Fatalf("POST /api/transpose = %d, want 200; body: %s", w.Code, w.Body)
 	}
-	if ct := w.Header().Get("Content-Type"); ct != "audio/midi" {
-		t.Errorf("Content-Type = %q, want audio/midi", ct)
+	var resp models.TransposeResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp
+}
+
+func TestTranspose_VeryHighWarning(t *testing.T) {
+	// The B and F# shapes top out at F#4; a capo at 11 sounds them up to F5.
+	resp := postTranspose(t, map[string]interface{}{
+		"from_key":   "C",
+		"to_key":     "B",
+		"chords":     []string{"C", "G"},
+		"instrument": "guitar",
+		"capo":       11,
+	})
+	if len(resp.Warnings) != 1 {
+		t.Fatalf("warnings = %v, want one", resp.Warnings)
 	}
-	midi := w.Body.Bytes()
-	if string(midi[0:4]) != "MThd" {
-		t.Errorf("response is not a valid MIDI file")
+	if w := resp.Warnings[0]; !strings.HasPrefix(w, "B, F# sit very high") || !strings.Contains(w, "F5") {
+		t.Errorf("warning = %q, want \"B, F# sit very high\" up to F5", w)
 	}
 }
 
-func TestGenerateMidi_EmptyChords(t *testing.T) {
+func TestTranspose_NoWarningForDownwardShift(t *testing.T) {
+	// G → F is a whole step down: F, A# and C all have ordinary shapes.
+	resp := postTranspose(t, map[string]interface{}{
+		"from_key":   "G",
+		"to_key":     "F",
+		"chords":     []string{"G", "C", "D"},
+		"instrument": "guitar",
+	})
+	if len(resp.Warnings) != 0 {
+		t.Errorf("warnings = %v, want none", resp.Warnings)
+	}
+}
+
+func TestTranspose_VeryLowWarning(t *testing.T) {
+	// The ukulele's F shape (2010) fits inside its bottom octave, C4–B4.
+	resp := postTranspose(t, map[string]interface{}{
+		"from_key":   "C",
+		"to_key":     "F",
+		"chords":     []string{"C", "G"},
+		"instrument": "ukulele",
+	})
+	if len(resp.Warnings) != 1 {
+		t.Fatalf("warnings = %v, want one", resp.Warnings)
+	}
+	if w := resp.Warnings[0]; !strings.HasPrefix(w, "F sits very low") || !strings.Contains(w, "C4") {
+		t.Errorf("warning = %q, want \"F sits very low\" down to C4", w)
+	}
+}
+
+func TestTranspose_NoWarningForSmallShift(t *testing.T) {
+	resp := postTranspose(t, map[string]interface{}{
+		"from_key":   "C",
+		"to_key":     "D",
+		"chords":     []string{"C", "G", "Am", "F"},
+		"instrument": "guitar",
+	})
+	if len(resp.Warnings) != 0 {
+		t.Errorf("warnings = %v, want none", resp.Warnings)
+	}
+}
+
+func TestTranspose_UnknownInstrument(t *testing.T) {
 	body, _ := json.Marshal(map[string]interface{}{
-		"chords":  []string{},
-		"tempo":   120,
-		"pattern": "quarter",
-		"octave":  4,
+		"from_key": "C", "to_key": "D", "chords": []string{"C"}, "instrument": "theremin",
 	})
 	r := newRouter()
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req, _ := http.NewRequest("POST", "/api/transpose", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	r.ServeHTTP(w, req)
 
-	// "chords" is required binding; empty slice should fail binding or be accepted
-	// depending on validation. Either 400 or 200 with minimal MIDI is acceptable.
-	// The important thing is it must not 500.
-	if w.Code == http.StatusInternalServerError {
-		t.Errorf("empty chords returned 500 (should be 400 or 200 with empty track)")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown instrument = %d, want 400", w.Code)
 	}
 }
 
-func TestGenerateMidi_DefaultsApplied(t *testing.T) {
-	// Omit optional fields; server should apply defaults and return 200
-	body, _ := json.Marshal(map[string]interface{}{
-		"chords": []string{"Am"},
-	})
+// ── /api/chords/:chord/instruments ───────────────────────────────────────
+
+func getChordInstruments(t *testing.T, path string) []string {
+	t.Helper()
 	r := newRouter()
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
+	req, _ := http.NewRequest("GET", path, nil)
 	r.ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
-		t.Errorf("POST /api/midi with defaults = %d, want 200; body: %s", w.Code, w.Body)
+		t.Fatalf("GET %s = %d, want 200; body: %s", path, w.Code, w.Body)
+	}
+	var resp struct {
+		Instruments []string `json:"instruments"`
 	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp.Instruments
 }
 
-func TestGenerateMidi_AllPatterns(t *testing.T) {
-	for pattern := range validPatterns {
-		t.Run(pattern, func(t *testing.T) {
-			body, _ := json.Marshal(map[string]interface{}{
-				"chords":  []string{"C", "Am"},
-				"tempo":   120,
-				"pattern": pattern,
-				"octave":  4,
-				"beats":   4,
-			})
-			r := newRouter()
-			w := httptest.NewRecorder()
-			req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
-			req.Header.Set("Content-Type", "application/json")
-			r.ServeHTTP(w, req)
+func TestGetChordInstruments_CommonChord(t *testing.T) {
+	got := map[string]bool{}
+	for _, inst := range getChordInstruments(t, "/api/chords/C/instruments") {
+		got[inst] = true
+	}
+	for _, want := range []string{"guitar", "ukulele"} {
+		if !got[want] {
+			t.Errorf("C should be playable on %s, got %v", want, got)
+		}
+	}
+}
 
-			if w.Code != http.StatusOK {
-				t.Errorf("pattern %q returned %d, want 200; body: %s", pattern, w.Code, w.Body)
-			}
-		})
+func TestGetChordInstruments_SharpAndFlat(t *testing.T) {
+	sharp := getChordInstruments(t, "/api/chords/C%23m/instruments")
+	if len(sharp) == 0 {
+		t.Fatal("C#m should be playable on at least one instrument")
+	}
+	flat := getChordInstruments(t, "/api/chords/Dbm/instruments")
+	if len(flat) != len(sharp) {
+		t.Errorf("Dbm instruments = %v, want same as C#m %v", flat, sharp)
+	}
+}
+
+// ── /api/open-strings ─────────────────────────────────────────────────────
+
+func TestGetOpenStrings_StandardGuitar(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/open-strings/guitar?tuning=standard", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/open-strings/guitar = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp models.OpenStringsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if want := []int{40, 45, 50, 55, 59, 64}; !reflect.DeepEqual(resp.OpenMidi, want) {
+		t.Errorf("openMidi = %v, want %v", resp.OpenMidi, want)
+	}
+	if want := []string{"E2", "A2", "D3", "G3", "B3", "E4"}; !reflect.DeepEqual(resp.Notes, want) {
+		t.Errorf("notes = %v, want %v", resp.Notes, want)
+	}
+}
+
+func TestGetOpenStrings_Errors(t *testing.T) {
+	r := newRouter()
+	for _, path := range []string{
+		"/api/open-strings/piano",
+		"/api/open-strings/guitar?tuning=nashville-x",
+		"/api/open-strings/kazoo",
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want 400", path, w.Code)
+		}
+	}
+}
+
+// ── /api/chords/batch ─────────────────────────────────────────────────────
+
+func TestBatchChords_Guitar(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"instrument": "guitar",
+		"chords":     []string{"C", "G", "Am"},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/chords/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/chords/batch = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if _, ok := resp["C"]; !ok {
+		t.Error("response missing 'C' key")
+	}
+}
+
+func TestBatchChords_Ordered(t *testing.T) {
+	chords := []string{"G", "C", "Xyz", "G"}
+	body, _ := json.Marshal(map[string]interface{}{
+		"instrument": "guitar",
+		"chords":     chords,
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/chords/batch?ordered=true", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/chords/batch?ordered=true = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp []models.OrderedChordVariants
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response is not an array: %v", err)
+	}
+	if len(resp) != len(chords) {
+		t.Fatalf("got %d entries, want %d", len(resp), len(chords))
+	}
+	for i, entry := range resp {
+		if entry.Chord != chords[i] {
+			t.Errorf("entry %d = %q, want %q", i, entry.Chord, chords[i])
+		}
+	}
+	if resp[2].Variants == nil || len(resp[2].Variants) != 0 {
+		t.Errorf("unknown chord variants = %v, want empty array", resp[2].Variants)
+	}
+}
+
+func TestBatchChords_FretStats(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"instrument": "guitar",
+		"chords":     []string{"C"},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/chords/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	var resp models.BatchChordsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp["C"]) == 0 {
+		t.Fatal("response has no C variants")
+	}
+	open := resp["C"][0] // x32010
+	if open.SoundingStrings != 5 {
+		t.Errorf("open C soundingStrings = %d, want 5", open.SoundingStrings)
+	}
+	if open.LowestFret != 1 || open.HighestFret != 3 {
+		t.Errorf("open C frets = %d–%d, want 1–3", open.LowestFret, open.HighestFret)
+	}
+}
+
+func TestPositionVoicings_OpenPosition(t *testing.T) {
+	chords := []string{"C", "G", "Am"}
+	body, _ := json.Marshal(map[string]interface{}{
+		"instrument": "guitar",
+		"chords":     chords,
+		"minFret":    0,
+		"maxFret":    3,
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/position-voicings", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/position-voicings = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp []models.OrderedChordVariants
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(resp) != len(chords) {
+		t.Fatalf("got %d entries, want %d", len(resp), len(chords))
+	}
+	for i, entry := range resp {
+		if entry.Chord != chords[i] {
+			t.Errorf("entry %d = %q, want %q", i, entry.Chord, chords[i])
+		}
+		if len(entry.Variants) == 0 {
+			t.Errorf("%s has no open-position voicing", entry.Chord)
+		}
+		for _, v := range entry.Variants {
+			if v.HighestFret > 3 {
+				t.Errorf("%s %q reaches fret %d, outside 0–3", entry.Chord, v.Name, v.HighestFret)
+			}
+		}
+	}
+	// The C barre at the 8th fret must be filtered out.
+	for _, v := range resp[0].Variants {
+		if v.LowestFret >= 8 {
+			t.Errorf("C variant %q at fret %d returned for window 0–3", v.Name, v.LowestFret)
+		}
+	}
+}
+
+func TestGetChords_PianoSoundingStrings(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/chords/piano", nil)
+	r.ServeHTTP(w, req)
+
+	var resp models.ChordDiagrams
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp["C"]) == 0 || resp["C"][0].SoundingStrings != 3 {
+		t.Errorf("piano C = %+v, want soundingStrings 3", resp["C"])
+	}
+}
+
+func TestGetChords_PrimaryVariants(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/chords/guitar?variants=primary", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/chords/guitar?variants=primary = %d, want 200", w.Code)
+	}
+	var resp models.ChordDiagrams
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	all, _ := loadChordDiagrams("guitar")
+	if len(resp) != len(all) {
+		t.Errorf("got %d chords, want %d", len(resp), len(all))
+	}
+	for chord, variants := range resp {
+		if len(variants) != 1 {
+			t.Errorf("%s has %d variants, want 1", chord, len(variants))
+		} else if variants[0].Name != all[chord][0].Name {
+			t.Errorf("%s variant = %q, want the first, %q", chord, variants[0].Name, all[chord][0].Name)
+		}
+	}
+}
+
+func TestGetChords_VariantsInvalid(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/chords/guitar?variants=some", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("variants=some = %d, want 400", w.Code)
+	}
+}
+
+func postCompare(t *testing.T, fields map[string]interface{}) (int, models.CompareChordsResponse) {
+	t.Helper()
+	body, _ := json.Marshal(fields)
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/chords/compare", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	var resp models.CompareChordsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestCompareChords_CAndAm(t *testing.T) {
+	code, resp := postCompare(t, map[string]interface{}{
+		"instrument": "guitar", "chordA": "C", "chordB": "Am",
+	})
+	if code != http.StatusOK {
+		t.Fatalf("POST /api/chords/compare = %d, want 200", code)
+	}
+	if !reflect.DeepEqual(resp.Common, []string{"C", "E"}) {
+		t.Errorf("common = %v, want [C E]", resp.Common)
+	}
+	if !reflect.DeepEqual(resp.OnlyA, []string{"G"}) || !reflect.DeepEqual(resp.OnlyB, []string{"A"}) {
+		t.Errorf("onlyA = %v, onlyB = %v, want [G] and [A]", resp.OnlyA, resp.OnlyB)
+	}
+}
+
+func TestCompareChords_Piano(t *testing.T) {
+	code, resp := postCompare(t, map[string]interface{}{
+		"instrument": "piano", "chordA": "C", "chordB": "Am",
+	})
+	if code != http.StatusOK {
+		t.Fatalf("POST /api/chords/compare = %d, want 200", code)
+	}
+	if !reflect.DeepEqual(resp.Common, []string{"C", "E"}) {
+		t.Errorf("common = %v, want [C E]", resp.Common)
+	}
+}
+
+func TestCompareChords_UnknownChord(t *testing.T) {
+	code, _ := postCompare(t, map[string]interface{}{
+		"instrument": "guitar", "chordA": "C", "chordB": "Xyz",
+	})
+	if code != http.StatusNotFound {
+		t.Errorf("unknown chord = %d, want 404", code)
+	}
+}
+
+func TestBatchChords_UnknownInstrument(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"instrument": "kazoo",
+		"chords":     []string{"C"},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/chords/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown instrument should return 400, got %d", w.Code)
+	}
+}
+
+// ── /api/midi ─────────────────────────────────────────────────────────────
+
+func TestGenerateMidi_Basic(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":  []string{"C", "G"},
+		"tempo":   120,
+		"pattern": "quarter",
+		"octave":  4,
+		"beats":   4,
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/midi = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "audio/midi" {
+		t.Errorf("Content-Type = %q, want audio/midi", ct)
+	}
+	midi := w.Body.Bytes()
+	if string(midi[0:4]) != "MThd" {
+		t.Errorf("response is not a valid MIDI file")
+	}
+}
+
+func TestGenerateMidi_EmptyChords(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":  []string{},
+		"tempo":   120,
+		"pattern": "quarter",
+		"octave":  4,
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("empty chords = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	events := trackEvents(t, w.Body.Bytes())
+	if len(events) != 2 || events[0].data1 != 0x51 || events[1].data1 != 0x2F {
+		t.Errorf("empty chords track = %+v, want tempo + end-of-track", events)
+	}
+}
+
+func TestGenerateMidi_DefaultsApplied(t *testing.T) {
+	// Omit optional fields; server should apply defaults and return 200
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords": []string{"Am"},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("POST /api/midi with defaults = %d, want 200; body: %s", w.Code, w.Body)
+	}
+}
+
+func TestGenerateMidi_AllPatterns(t *testing.T) {
+	for pattern := range validPatterns {
+		t.Run(pattern, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{
+				"chords":   []string{"C", "Am"},
+				"tempo":    120,
+				"pattern":  pattern,
+				"octave":   4,
+				"beats":    4,
+				"rhythm":   []string{"q", "q", "h"},
+				"strumDSL": "D-DU-UDU",
+			})
+			r := newRouter()
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("pattern %q returned %d, want 200; body: %s", pattern, w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestGenerateMidi_CustomRhythmMustFillBar(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":  []string{"C"},
+		"pattern": "custom",
+		"beats":   4,
+		"rhythm":  []string{"q", "e", "e"},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("short rhythm should return 400, got %d; body: %s", w.Code, w.Body)
+	}
+}
+
+func TestGenerateMidi_StrumDSLInvalid(t *testing.T) {
+	for _, dsl := range []string{"D-DX", "DUD", ""} {
+		body, _ := json.Marshal(map[string]interface{}{
+			"chords":   []string{"C"},
+			"pattern":  "strum-dsl",
+			"strumDSL": dsl,
+		})
+		r := newRouter()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("strumDSL %q = %d, want 400", dsl, w.Code)
+		}
+	}
+}
+
+func TestGenerateMidi_PatternSequenceUnknown(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":          []string{"C", "G"},
+		"patternSequence": []string{"pop-strum", "polka"},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown sequence pattern should return 400, got %d", w.Code)
+	}
+}
+
+func TestGenerateMidi_JSONFormatWarnings(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":   []string{"G"},
+		"pattern":  "whole",
+		"openMidi": []int{40, 45, 50, 55, 59, 64},
+		"frets":    [][]string{{"x", "x", "x", "x", "3", "3"}},
+		"format":   "json",
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/midi = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp MidiJSONResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if len(resp.Midi) < 4 || string(resp.Midi[:4]) != "MThd" {
+		t.Error("midi field is not a valid MIDI file")
+	}
+	if len(resp.Warnings) != 1 {
+		t.Errorf("got %d warnings, want 1: %v", len(resp.Warnings), resp.Warnings)
+	}
+}
+
+func TestGenerateMidi_Stems(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":  []string{"C", "Am", "F", "G"},
+		"pattern": "pop-strum",
+		"octave":  4,
+		"drums":   true,
+		"stems":   true,
+		"format":  "json",
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/midi = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp MidiJSONResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if resp.Stems == nil {
+		t.Fatal("stems missing from response")
+	}
+	// maxVoices is the most notes sounding at once in a one-track stem.
+	maxVoices := func(name string, midi []byte) int {
+		chunks := trackChunks(t, midi)
+		if len(chunks) != 1 {
+			t.Fatalf("%s stem has %d tracks, want 1", name, len(chunks))
+		}
+		sounding, most := 0, 0
+		for _, ev := range parseTrack(chunks[0]) {
+			if ev.status == 0xFF || ev.data1 == 0 {
+				continue // meta events and rests
+			}
+			if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+				sounding++
+				most = max(most, sounding)
+			} else {
+				sounding--
+			}
+		}
+		return most
+	}
+	chord, bass := maxVoices("chord", resp.Stems.Chord), maxVoices("bass", resp.Stems.Bass)
+	if bass != 1 || chord <= bass {
+		t.Errorf("max simultaneous notes: chord %d, bass %d; want bass 1 and chord more", chord, bass)
+	}
+
+	body, _ = json.Marshal(map[string]interface{}{"chords": []string{"C"}, "stems": true})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("stems without format json = %d, want 400", w.Code)
+	}
+}
+
+func TestGenerateMidi_JSONAppliedSettings(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":          []string{"C", "G"},
+		"tempo":           400,
+		"octave":          3,
+		"swing":           60,
+		"releaseVelocity": 40,
+		"format":          "json",
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/midi = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp MidiJSONResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	want := AppliedSettings{
+		Tempo:           120,
+		Pattern:         "quarter",
+		Swing:           60,
+		Beats:           4,
+		Octave:          3,
+		PPQ:             ticksPerQuarter,
+		ReleaseVelocity: 40,
+		Clamped:         []string{"tempo 400 out of range, used 120"},
+	}
+	if !reflect.DeepEqual(resp.Applied, want) {
+		t.Errorf("appliedSettings = %+v, want %+v", resp.Applied, want)
+	}
+}
+
+func TestGenerateMidi_ValidateOnly(t *testing.T) {
+	post := func(payload map[string]interface{}) (*httptest.ResponseRecorder, MidiValidationResponse) {
+		body, _ := json.Marshal(payload)
+		r := newRouter()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/midi?validate=true", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		var resp MidiValidationResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("response is not JSON: %v; body: %q", err, w.Body)
+		}
+		return w, resp
+	}
+
+	w, resp := post(map[string]interface{}{"chords": []string{"C"}, "pattern": "polka"})
+	if w.Code != http.StatusOK || strings.HasPrefix(w.Body.String(), "MThd") {
+		t.Fatalf("invalid request = %d %q, want a 200 JSON report", w.Code, w.Body)
+	}
+	if resp.Valid || !reflect.DeepEqual(resp.Errors, []string{"unknown pattern: polka"}) || resp.Applied != nil {
+		t.Errorf("invalid request report = %+v, want the unknown pattern error only", resp)
+	}
+
+	_, resp = post(map[string]interface{}{"chords": []string{"C"}, "octave": 3})
+	if !resp.Valid || len(resp.Errors) != 0 || resp.Applied == nil {
+		t.Fatalf("valid request report = %+v, want valid with applied settings", resp)
+	}
+	if resp.Applied.Pattern != "quarter" || resp.Applied.Tempo != 120 || resp.Applied.Octave != 3 {
+		t.Errorf("applied = %+v, want the defaulted quarter pattern at 120 BPM, octave 3", resp.Applied)
+	}
+}
+
+func TestGenerateMidi_JSONFingerings(t *testing.T) {
+	frets := []string{"x", "3", "2", "0", "1", "0"}
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":     []string{"C", "G"},
+		"instrument": "guitar",
+		"openMidi":   []int{40, 45, 50, 55, 59, 64},
+		"frets":      [][]string{frets, {"9", "9", "9", "9", "9", "9"}},
+		"format":     "json",
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/midi = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp MidiJSONResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Fingerings) != 2 {
+		t.Fatalf("got %d fingerings, want 2", len(resp.Fingerings))
+	}
+	fingers := resp.Fingerings[0].Fingers
+	if len(fingers) != len(frets) {
+		t.Fatalf("C fingers = %v, want one per string", fingers)
+	}
+	for i, f := range fingers {
+		// Only fretted strings (not open or muted) take a finger.
+		if fretted := frets[i] != "x" && frets[i] != "0"; fretted != (f != "") {
+			t.Errorf("string %d: fret %q has finger %q", i, frets[i], f)
+		}
+	}
+	if resp.Fingerings[1].Fingers != nil {
+		t.Errorf("unmatched G frets have fingers %v, want null", resp.Fingerings[1].Fingers)
+	}
+}
+
+func TestGenerateMidi_FretRowLengthMismatch(t *testing.T) {
+	payload := map[string]interface{}{
+		"chords":   []string{"C"},
+		"openMidi": []int{40, 45, 50, 55, 59, 64},
+		"frets":    [][]string{{"3", "2", "0", "1", "0"}},
+	}
+	body, _ := json.Marshal(payload)
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("5-string fret row on a 6-string tuning = %d, want 400", w.Code)
+	}
+
+	// lenient mode keeps the old drop-extra-strings behaviour
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/midi?lenient=true", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("lenient mismatch = %d, want 200; body: %s", w.Code, w.Body)
+	}
+}
+
+func TestGenerateMidi_FretRowInstrumentMismatch(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":     []string{"C"},
+		"instrument": "ukulele",
+		"frets":      [][]string{{"x", "3", "2", "0", "1", "0"}},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("6-string fret row for ukulele = %d, want 400", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "ukulele has 4") {
+		t.Errorf("error = %s, want it to name the ukulele's 4 strings", w.Body)
+	}
+}
+
+func TestGenerateMidi_EmptyFretRowAllowed(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":   []string{"C", "Xyz"},
+		"openMidi": []int{40, 45, 50, 55, 59, 64},
+		"frets":    [][]string{{"x", "3", "2", "0", "1", "0"}, {}},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("empty fret row = %d, want 200; body: %s", w.Code, w.Body)
+	}
+}
+
+func TestGenerateMidi_InstrumentRangeFold(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":     []string{"C", "G"},
+		"pattern":    "whole",
+		"octave":     7,
+		"instrument": "guitar",
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/midi = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	// Octave 7 puts C at MIDI 96, above the guitar's top note (88).
+	for _, ev := range trackEvents(t, w.Body.Bytes()) {
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 && (ev.data1 < 40 || ev.data1 > 88) {
+			t.Errorf("note %d outside guitar range 40–88", ev.data1)
+		}
+	}
+}
+
+func TestGenerateMidi_UnknownInstrument(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":     []string{"C"},
+		"instrument": "theremin",
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown instrument = %d, want 400", w.Code)
+	}
+}
+
+func TestGenerateMidi_InlineChordBeats(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":  []string{"C:2", "G", "Am:1"},
+		"pattern": "whole",
+		"octave":  4,
+		"beats":   4,
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/midi = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	// Each chord's first note-off lands after its own duration.
+	var lengths []uint32
+	var roots []byte
+	for _, ev := range trackEvents(t, w.Body.Bytes()) {
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 && ev.delta == 0 && len(roots) == len(lengths) {
+			roots = append(roots, ev.data1)
+		}
+		if ev.status&0xF0 == 0x80 && ev.delta > 0 {
+			lengths = append(lengths, ev.delta)
+		}
+	}
+	if want := []byte{60, 67, 69}; !bytes.Equal(roots, want) {
+		t.Errorf("chord roots = %v, want %v (names parsed without durations)", roots, want)
+	}
+	want := []uint32{2 * ticksPerQuarter, 4 * ticksPerQuarter, ticksPerQuarter}
+	if !reflect.DeepEqual(lengths, want) {
+		t.Errorf("chord lengths = %v, want %v", lengths, want)
+	}
+}
+
+func TestGenerateMidi_MaxRootMidi(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":      []string{"E", "A"},
+		"pattern":     "whole",
+		"octave":      4,
+		"maxRootMidi": 62, // D4
+		"format":      "json",
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/midi = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp MidiJSONResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	// E4 (64) comes down two semitones to D4.
+	if resp.RootShift != -2 {
+		t.Errorf("rootShift = %d, want -2", resp.RootShift)
+	}
+	var ons []byte
+	for _, ev := range trackEvents(t, resp.Midi) {
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+			ons = append(ons, ev.data1)
+		}
+	}
+	if len(ons) == 0 || ons[0] != 62 {
+		t.Errorf("first chord root = %v, want 62", ons)
+	}
+	if want := []byte{62, 66, 69, 67, 71, 74}; !bytes.Equal(ons, want) {
+		t.Errorf("notes = %v, want the whole progression down a tone %v", ons, want)
+	}
+}
+
+func TestGenerateMidi_MaxRootMidiAlreadyFits(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":      []string{"C"},
+		"octave":      4,
+		"maxRootMidi": 62,
+		"format":      "json",
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	var resp MidiJSONResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.RootShift != 0 {
+		t.Errorf("C4 under D4: code %d rootShift %d, want 200 and 0", w.Code, resp.RootShift)
+	}
+}
+
+func TestGenerateMidi_StrumSpreadTooWide(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":   []string{"C"},
+		"pattern":  "pop-strum",
+		"upSpread": 31,
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("upSpread 31 = %d, want 400", w.Code)
+	}
+}
+
+func TestGenerateMidi_UnknownBackbeat(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":   []string{"C"},
+		"drums":    true,
+		"backbeat": "cowbell-ish",
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown backbeat = %d, want 400", w.Code)
+	}
+}
+
+func TestGenerateMidi_InlineChordBeatsInvalid(t *testing.T) {
+	for _, chords := range [][]string{{"C:0"}, {"C:x"}, {"C:"}, {"C:17"}} {
+		body, _ := json.Marshal(map[string]interface{}{"chords": chords})
+		r := newRouter()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("chords %v = %d, want 400", chords, w.Code)
+		}
+	}
+}
+
+func TestGenerateMidi_OpenCentsNeedsPerStringChannel(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":    []string{"C"},
+		"openMidi":  []int{40, 45, 50, 55, 59, 64},
+		"frets":     [][]string{{"x", "3", "2", "0", "1", "0"}},
+		"openCents": []int{0, 0, 0, 0, -14, 0},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("openCents without perStringChannel = %d, want 400", w.Code)
+	}
+}
+
+func TestGenerateMidi_AttackEachBar(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":        []string{"C:12", "G"},
+		"pattern":       "whole",
+		"octave":        4,
+		"attackEachBar": true,
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi/events", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/midi/events = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var events []NoteEvent
+	json.Unmarshal(w.Body.Bytes(), &events)
+	var attacks []uint32
+	for _, ev := range events {
+		if ev.Type == "noteOn" && ev.Note == 60 {
+			attacks = append(attacks, ev.Tick)
+		}
+	}
+	// The three-bar C is struck on each of its bar lines.
+	bar := uint32(4 * ticksPerQuarter)
+	if want := []uint32{0, bar, 2 * bar}; !reflect.DeepEqual(attacks, want) {
+		t.Errorf("C4 attacks at %v, want %v", attacks, want)
+	}
+
+	// Re-striking every bar contradicts holding ties.
+	body, _ = json.Marshal(map[string]interface{}{
+		"chords":        []string{"C", "C"},
+		"pattern":       "whole",
+		"attackEachBar": true,
+		"tieRepeats":    true,
+	})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("attackEachBar with tieRepeats = %d, want 400", w.Code)
+	}
+}
+
+// ── /api/chords/:instrument/:chord/midi ──────────────────────────────────
+
+func TestGetChordMidi_GuitarC(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/chords/guitar/C/midi?pattern=arpeggio-up&variant=0", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET chord midi = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "audio/midi" {
+		t.Errorf("Content-Type = %q, want audio/midi", ct)
+	}
+	if midi := w.Body.Bytes(); len(midi) < 4 || string(midi[0:4]) != "MThd" {
+		t.Errorf("response is not a valid MIDI file")
+	}
+}
+
+func TestGetChordMidiAll_OnePerVariant(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/chords/guitar/C/midi-all", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET midi-all = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var clips [][]byte
+	if err := json.Unmarshal(w.Body.Bytes(), &clips); err != nil {
+		t.Fatalf("response is not an array of base64 clips: %v", err)
+	}
+	diagrams, _ := loadChordDiagrams("guitar")
+	if len(clips) != len(diagrams["C"]) {
+		t.Fatalf("got %d clips, want one per variant (%d)", len(clips), len(diagrams["C"]))
+	}
+	for i, midi := range clips {
+		if len(midi) < 4 || string(midi[0:4]) != "MThd" {
+			t.Errorf("clip %d is not a valid MIDI file", i)
+		}
+	}
+}
+
+func TestGetChordMidiAll_UnknownChord(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/chords/guitar/Xyz/midi-all", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown chord = %d, want 404", w.Code)
+	}
+}
+
+func TestGetChordMidi_Errors(t *testing.T) {
+	cases := []struct {
+		path string
+		want int
+	}{
+		{"/api/chords/guitar/Xyz/midi", http.StatusNotFound},
+		{"/api/chords/theremin/C/midi", http.StatusBadRequest},
+		{"/api/chords/guitar/C/midi?pattern=nope", http.StatusBadRequest},
+		{"/api/chords/guitar/C/midi?variant=99", http.StatusBadRequest},
+	}
+	r := newRouter()
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", tc.path, nil)
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("GET %s = %d, want %d", tc.path, w.Code, tc.want)
+		}
+	}
+}
+
+// ── /api/shift-frets ──────────────────────────────────────────────────────
+
+func postShiftFrets(t *testing.T, frets []string, semitones int) (int, models.ShiftFretsResponse) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"frets": frets, "semitones": semitones})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/shift-frets", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	var resp models.ShiftFretsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestShiftFrets_EShapeBarre(t *testing.T) {
+	// F barre (E shape, 1st fret) up two frets → G barre at the 3rd.
+	code, resp := postShiftFrets(t, []string{"1", "3", "3", "2", "1", "1"}, 2)
+	if code != http.StatusOK {
+		t.Fatalf("POST /api/shift-frets = %d, want 200", code)
+	}
+	want := []string{"3", "5", "5", "4", "3", "3"}
+	if !reflect.DeepEqual(resp.Frets, want) {
+		t.Errorf("frets = %v, want %v", resp.Frets, want)
+	}
+	if len(resp.Unplayable) != 0 {
+		t.Errorf("unplayable = %v, want none", resp.Unplayable)
+	}
+}
+
+func TestShiftFrets_BelowNutMuted(t *testing.T) {
+	// Open C shape down one fret: the open strings fall off the nut.
+	code, resp := postShiftFrets(t, []string{"x", "3", "2", "0", "1", "0"}, -1)
+	if code != http.StatusOK {
+		t.Fatalf("POST /api/shift-frets = %d, want 200", code)
+	}
+	want := []string{"x", "2", "1", "x", "0", "x"}
+	if !reflect.DeepEqual(resp.Frets, want) {
+		t.Errorf("frets = %v, want %v", resp.Frets, want)
+	}
+	if !reflect.DeepEqual(resp.Unplayable, []int{3, 5}) {
+		t.Errorf("unplayable = %v, want [3 5]", resp.Unplayable)
+	}
+}
+
+func TestShiftFrets_InvalidFret(t *testing.T) {
+	if code, _ := postShiftFrets(t, []string{"x", "a"}, 1); code != http.StatusBadRequest {
+		t.Errorf("invalid fret = %d, want 400", code)
+	}
+}
+
+func TestGenerateMidi_CustomIntervalsInvalid(t *testing.T) {
+	for _, custom := range []map[string][]int{{"oct": {}}, {"oct": {0, 128}}, {"oct": {-1, 0}}} {
+		body, _ := json.Marshal(map[string]interface{}{"chords": []string{"Coct"}, "customIntervals": custom})
+		r := newRouter()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/midi", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("customIntervals %v = %d, want 400", custom, w.Code)
+		}
 	}
 }
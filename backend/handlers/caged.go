@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"guitartutor/backend/models"
+)
+
+// cagedTemplate is an open-position chord shape in standard tuning that can
+// be moved up the neck by barring across its open strings.
+type cagedTemplate struct {
+	shape string
+	root  int   // semitone index of the open chord's root
+	frets []int // low E first; -1 = muted
+}
+
+// cagedTemplates holds the five open shapes for each supported quality, in
+// CAGED order.
+var cagedTemplates = map[string][]cagedTemplate{
+	"": {
+		{"C", 0, []int{-1, 3, 2, 0, 1, 0}},
+		{"A", 9, []int{-1, 0, 2, 2, 2, 0}},
+		{"G", 7, []int{3, 2, 0, 0, 0, 3}},
+		{"E", 4, []int{0, 2, 2, 1, 0, 0}},
+		{"D", 2, []int{-1, -1, 0, 2, 3, 2}},
+	},
+	"m": {
+		{"C", 0, []int{-1, 3, 1, 0, 1, -1}},
+		{"A", 9, []int{-1, 0, 2, 2, 1, 0}},
+		{"G", 7, []int{3, 1, 0, 0, 3, 3}},
+		{"E", 4, []int{0, 2, 2, 0, 0, 0}},
+		{"D", 2, []int{-1, -1, 0, 2, 3, 1}},
+	},
+}
+
+// cagedShapes moves each template for quality up to root, returning the
+// shapes ordered by base fret. ok is false for unsupported qualities.
+func cagedShapes(root int, quality string) (shapes []models.CagedShape, ok bool) {
+	templates, ok := cagedTemplates[quality]
+	if !ok {
+		return nil, false
+	}
+	for _, tpl := range templates {
+		shift := ((root-tpl.root)%12 + 12) % 12
+		frets := make([]string, len(tpl.frets))
+		for i, f := range tpl.frets {
+			if f < 0 {
+				frets[i] = "x"
+			} else {
+				frets[i] = strconv.Itoa(f + shift)
+			}
+		}
+		shapes = append(shapes, models.CagedShape{Shape: tpl.shape, BaseFret: shift, BarreFret: shift, Frets: frets})
+	}
+	sort.SliceStable(shapes, func(i, j int) bool { return shapes[i].BaseFret < shapes[j].BaseFret })
+	return shapes, true
+}
+
+// GetCagedShapes handles GET /api/caged/:chord, listing the five CAGED
+// positions of a major or minor chord across the guitar neck. Sharps must be
+// URL-encoded ("C%23m").
+func GetCagedShapes(c *gin.Context) {
+	chord := c.Param("chord")
+	root := chordRootIndex(chord)
+	if root == -1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unrecognised chord: " + chord})
+		return
+	}
+	quality := chordSuffix(chord)
+	shapes, ok := cagedShapes(root, quality)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CAGED shapes are only available for major and minor chords"})
+		return
+	}
+	c.JSON(http.StatusOK, models.CagedResponse{Chord: chord, Shapes: shapes})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"guitartutor/backend/models"
+)
+
+func TestGetCagedShapes_CMajor(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/caged/C", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/caged/C = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp models.CagedResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(resp.Shapes) != 5 {
+		t.Fatalf("got %d shapes, want 5", len(resp.Shapes))
+	}
+	wantOrder := []string{"C", "A", "G", "E", "D"}
+	for i, s := range resp.Shapes {
+		if s.Shape != wantOrder[i] {
+			t.Errorf("shapes[%d] = %s shape, want %s", i, s.Shape, wantOrder[i])
+		}
+		if i > 0 && s.BaseFret <= resp.Shapes[i-1].BaseFret {
+			t.Errorf("base frets not ascending: %d after %d", s.BaseFret, resp.Shapes[i-1].BaseFret)
+		}
+	}
+}
+
+func TestCagedShapes_SoundTheChord(t *testing.T) {
+	guitar := []int{40, 45, 50, 55, 59, 64}
+	for quality, third := range map[string]int{"": 4, "m": 3} {
+		for root := 0; root < 12; root++ {
+			shapes, _ := cagedShapes(root, quality)
+			want := map[int]bool{root: true, (root + third) % 12: true, (root + 7) % 12: true}
+			for _, s := range shapes {
+				for _, n := range fretsToMidi(s.Frets, guitar) {
+					if !want[int(n)%12] {
+						t.Errorf("%s%s %s shape %v sounds pitch class %d", chromatic[root], quality, s.Shape, s.Frets, int(n)%12)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestGetCagedShapes_UnsupportedQuality(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/caged/Cmaj7", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("GET /api/caged/Cmaj7 = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import "sort"
+
+// ── Percussion (General MIDI channel 10) ────────────────────────────────────
+
+// drumChannel is GM channel 10 (zero-based 9), reserved for percussion.
+const drumChannel = 9
+
+// General MIDI percussion note numbers.
+const (
+	gmMetronomeClick = 33
+	gmMetronomeBell  = 34
+	gmKick           = 36
+	gmRimshot        = 37
+	gmSnare          = 38
+	gmClosedHiHat    = 42
+	gmLowTom         = 45
+	gmOpenHiHat      = 46
+	gmMidTom         = 47
+	gmHighTom        = 50
+)
+
+// gmDrums maps the drum names requests may use to General MIDI percussion notes.
+var gmDrums = map[string]byte{
+	"kick":         gmKick,
+	"rimshot":      gmRimshot,
+	"snare":        gmSnare,
+	"hihat-closed": gmClosedHiHat,
+	"hihat-open":   gmOpenHiHat,
+	"tom-low":      gmLowTom,
+	"tom-mid":      gmMidTom,
+	"tom-high":     gmHighTom,
+	"click":        gmMetronomeClick,
+	"bell":         gmMetronomeBell,
+}
+
+// drumHitTicks is how long each drum note is held before its note-off.
+const drumHitTicks = ticksPerQuarter / 8
+
+// drumHit is a single percussion strike at an absolute tick.
+type drumHit struct {
+	tick uint32
+	note byte
+	vel  byte
+}
+
+// grooveBar returns a basic rock beat for one bar starting at start: kick on
+// the odd beats, backbeat (normally the snare) on the even beats and closed
+// hi-hat on every eighth.
+func grooveBar(start uint32, beats int, backbeat byte) []drumHit {
+	eighthTicks := uint32(ticksPerQuarter / 2)
+	var hits []drumHit
+	for beat := 0; beat < beats; beat++ {
+		t := start + uint32(beat)*ticksPerQuarter
+		if beat%2 == 0 {
+			hits = append(hits, drumHit{t, gmKick, 110})
+		} else {
+			hits = append(hits, drumHit{t, backbeat, 100})
+		}
+		hits = append(hits, drumHit{t, gmClosedHiHat, 80}, drumHit{t + eighthTicks, gmClosedHiHat, 65})
+	}
+	return hits
+}
+
+// fillBar returns a turnaround fill for one bar: snare eighths for the first
+// half, then sixteenths rolling down the toms into the next downbeat.
+func fillBar(start uint32, beats int) []drumHit {
+	eighthTicks := uint32(ticksPerQuarter / 2)
+	sixteenthTicks := uint32(ticksPerQuarter / 4)
+	toms := []byte{gmHighTom, gmHighTom, gmMidTom, gmMidTom, gmLowTom, gmLowTom, gmLowTom, gmLowTom}
+	half := beats / 2
+	hits := []drumHit{{start, gmKick, 110}}
+	for e := 0; e < half*2; e++ {
+		hits = append(hits, drumHit{start + uint32(e)*eighthTicks, gmSnare, 90 + byte(e%2)*10})
+	}
+	tomStart := start + uint32(half)*ticksPerQuarter
+	for s := 0; s < (beats-half)*4; s++ {
+		tom := toms[s*len(toms)/((beats-half)*4)]
+		hits = append(hits, drumHit{tomStart + uint32(s)*sixteenthTicks, tom, 100})
+	}
+	return hits
+}
+
+// introTicks is the length of the intro strum bar, if the request has one.
+// Percussion tracks start after it so they line up with the first chord.
+func introTicks(req MidiRequest) uint32 {
+	if req.IntroStrum && len(req.OpenMidi) > 0 {
+		return uint32(ticksPerQuarter * req.Beats)
+	}
+	return 0
+}
+
+// leadTicks is where the first chord (or intro strum) starts: StartTick plus
+// any intro bar. Percussion tracks begin here.
+func leadTicks(req MidiRequest) uint32 {
+	return req.StartTick + introTicks(req)
+}
+
+// buildDrumTrack renders the percussion track (MTrk data) for a request: one
+// groove bar per chord, optionally replacing the last bar of each pass with a fill.
+func buildDrumTrack(req MidiRequest) []byte {
+	backbeat := byte(gmSnare)
+	if req.Backbeat != "" {
+		backbeat = gmDrums[req.Backbeat]
+	}
+	barStart := leadTicks(req)
+	var hits []drumHit
+	for bar := 0; bar < totalBars(req); bar++ {
+		beats := beatsFor(req, bar%len(req.Chords))
+		if req.FillLastBar && bar%len(req.Chords) == len(req.Chords)-1 {
+			hits = append(hits, fillBar(barStart, beats)...)
+		} else {
+			hits = append(hits, grooveBar(barStart, beats, backbeat)...)
+		}
+		barStart += uint32(ticksPerQuarter * beats)
+	}
+	return append(encodeDrumHits(hits, barStart, req.ReleaseVelocity), endOfTrack()...)
+}
+
+// clickTicks maps ClickSubdivision values to the spacing between clicks.
+var clickTicks = map[string]uint32{
+	"quarter": ticksPerQuarter,
+	"eighth":  ticksPerQuarter / 2,
+}
+
+// buildClickTrack renders a metronome track clicking at req.ClickSubdivision
+// across every chord, with a bell on each bar's downbeat. With ClickDropAfter
+// set, passes after the first ClickDropAfter are left silent.
+func buildClickTrack(req MidiRequest) []byte {
+	step := clickTicks[req.ClickSubdivision]
+	var hits []drumHit
+	barStart := leadTicks(req)
+	for bar := 0; bar < totalBars(req); bar++ {
+		barEnd := barStart + uint32(ticksPerQuarter*beatsFor(req, bar%len(req.Chords)))
+		if req.ClickDropAfter > 0 && bar/len(req.Chords) >= req.ClickDropAfter {
+			barStart = barEnd
+			continue
+		}
+		for t := barStart; step > 0 && t < barEnd; t += step {
+			switch {
+			case t == barStart:
+				hits = append(hits, drumHit{t, gmMetronomeBell, 110})
+			case (t-barStart)%ticksPerQuarter == 0:
+				hits = append(hits, drumHit{t, gmMetronomeClick, 100})
+			default:
+				hits = append(hits, drumHit{t, gmMetronomeClick, 70})
+			}
+		}
+		barStart = barEnd
+	}
+	return append(encodeDrumHits(hits, barStart, req.ReleaseVelocity), endOfTrack()...)
+}
+
+// encodeDrumHits converts absolute-time hits into delta-timed note events on
+// the drum channel, padding with a final rest so the track lasts until end.
+func encodeDrumHits(hits []drumHit, end uint32, offVel byte) []byte {
+	type event struct {
+		tick uint32
+		on   bool
+		note byte
+		vel  byte
+	}
+	var events []event
+	for _, h := range hits {
+		events = append(events, event{h.tick, true, h.note, h.vel}, event{h.tick + drumHitTicks, false, h.note, offVel})
+	}
+	// Order by time; at equal ticks release before striking again.
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].tick != events[j].tick {
+			return events[i].tick < events[j].tick
+		}
+		return !events[i].on && events[j].on
+	})
+
+	var trk []byte
+	var now uint32
+	for _, ev := range events {
+		d := ev.tick - now
+		now = ev.tick
+		if ev.on {
+			trk = append(trk, noteOnEvent(d, drumChannel, ev.note, ev.vel)...)
+		} else {
+			trk = append(trk, noteOffEvent(d, drumChannel, ev.note, ev.vel)...)
+		}
+	}
+	if now < end {
+		trk = append(trk, noteOnEvent(0, drumChannel, 0, 0)...)
+		trk = append(trk, noteOffEvent(end-now, drumChannel, 0, offVel)...)
+	}
+	return trk
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NoteEvent is one note-on or note-off from a rendered progression, with its
+// absolute position in ticks (480 per quarter note).
+type NoteEvent struct {
+	Tick     uint32 `json:"tick"`
+	Type     string `json:"type"` // "noteOn" or "noteOff"
+	Note     byte   `json:"note"`
+	NoteName string `json:"noteName"` // e.g. "C4" (MIDI 60)
+	Velocity byte   `json:"velocity"`
+	Channel  byte   `json:"channel"` // 0 = chords, 9 = drums and click
+
+	// Articulation marks chord note-ons: "staccato" for the short, muted
+	// patterns and "accent" otherwise for strikes on a chord change or on a
+	// bar's downbeat.
+	Articulation string `json:"articulation,omitempty"`
+}
+
+// midiNoteName spells a MIDI note number with sharps and octave, e.g. 61 → "C#4".
+func midiNoteName(n byte) string {
+	return chromatic[n%12] + strconv.Itoa(int(n)/12-1)
+}
+
+// trackEvent is one decoded MTrk event. Channel events keep their data bytes
+// in data1/data2; meta events (status 0xFF) keep their type in data1 and
+// their payload in meta.
+type trackEvent struct {
+	delta  uint32
+	tick   uint32 // absolute position in the track
+	status byte
+	data1  byte
+	data2  byte
+	meta   []byte
+}
+
+// parseTrack decodes the events of one MTrk data chunk, as written by
+// buildTrack and friends (no running status, no SysEx).
+func parseTrack(trk []byte) []trackEvent {
+	var events []trackEvent
+	var tick uint32
+	for i := 0; i < len(trk); {
+		delta, next := readVarLen(trk, i)
+		tick += delta
+		ev := trackEvent{delta: delta, tick: tick, status: trk[next]}
+		i = next + 1
+		switch {
+		case ev.status == 0xFF:
+			ev.data1 = trk[i]
+			length, start := readVarLen(trk, i+1)
+			i = start + int(length)
+			ev.meta = trk[start:i]
+		case ev.status&0xF0 == 0xC0 || ev.status&0xF0 == 0xD0:
+			ev.data1 = trk[i] // program change, channel pressure
+			i++
+		default:
+			ev.data1, ev.data2 = trk[i], trk[i+1]
+			i += 2
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+// noteEvents decodes the note events of every track, merged in tick order.
+// The silent placeholder notes buildTrack writes for rests are left out.
+func noteEvents(tracks [][]byte) []NoteEvent {
+	events := []NoteEvent{}
+	for _, trk := range tracks {
+		for _, ev := range parseTrack(trk) {
+			kind, ch, note := ev.status&0xF0, ev.status&0x0F, ev.data1
+			if ev.status == 0xFF || (kind != 0x80 && kind != 0x90) {
+				continue // meta events, pitch bend
+			}
+			if note == 0 && ch == 0 {
+				continue // rest
+			}
+			typ := "noteOff"
+			if kind == 0x90 && ev.data2 > 0 {
+				typ = "noteOn"
+			}
+			events = append(events, NoteEvent{
+				Tick:     ev.tick,
+				Type:     typ,
+				Note:     note,
+				NoteName: midiNoteName(note),
+				Velocity: ev.data2,
+				Channel:  ch,
+			})
+		}
+	}
+	sort.SliceStable(events, func(a, b int) bool { return events[a].Tick < events[b].Tick })
+	return events
+}
+
+// staccatoPatterns play short, damped strikes.
+var staccatoPatterns = map[string]bool{"palm-mute-8th": true, "reggae-skank": true, "pop-stabs": true}
+
+// accentWindow is how soon after a chord change a note-on still counts as the
+// downbeat strike, allowing for strum spread and swing.
+const accentWindow = ticksPerQuarter / 4
+
+// annotateArticulations sets the Articulation of each chord note-on from the
+// pattern of the slot it falls in. Intro notes and drums are left unmarked.
+// Slot starts follow PushEighths, which lands every change after the first early.
+func annotateArticulations(req MidiRequest, events []NoteEvent) {
+	var push uint32
+	if totalBars(req) > 1 {
+		push = uint32(req.PushEighths) * (ticksPerQuarter / 2)
+	}
+	bar := uint32(ticksPerQuarter * req.Beats)
+	starts := []uint32{leadTicks(req)}
+	at := starts[0] // unpushed start of the next slot
+	for slot := 0; slot < totalBars(req); slot++ {
+		at += uint32(ticksPerQuarter * beatsFor(req, slot%len(req.Chords)))
+		starts = append(starts, at-push)
+	}
+	for i := range events {
+		ev := &events[i]
+		if ev.Type != "noteOn" || ev.Channel == drumChannel || ev.Tick < starts[0] {
+			continue
+		}
+		slot := sort.Search(len(starts), func(j int) bool { return starts[j] > ev.Tick }) - 1
+		if slot >= totalBars(req) {
+			continue
+		}
+		switch {
+		case staccatoPatterns[slotPattern(req, slot)]:
+			ev.Articulation = "staccato"
+		case ev.Tick-starts[slot] < accentWindow, (ev.Tick-starts[0])%bar < accentWindow:
+			ev.Articulation = "accent"
+		}
+	}
+}
+
+// GetMidiEvents handles POST /api/midi/events. It takes the same body as
+// POST /api/midi but returns the rendered note events as JSON instead of a file.
+func GetMidiEvents(c *gin.Context) {
+	req, ok := bindMidiRequest(c)
+	if !ok {
+		return
+	}
+	events := noteEvents(buildTracks(req))
+	annotateArticulations(req, events)
+	c.JSON(http.StatusOK, events)
+}
+
+// QuantizeRequest is the JSON body for POST /api/quantize: events in the
+// POST /api/midi/events format and the grid to snap them to.
+type QuantizeRequest struct {
+	Events []NoteEvent `json:"events" binding:"required"`
+	Grid   string      `json:"grid"` // "1/1", "1/2", "1/4", "1/8", "1/16" (default) or "1/32"
+}
+
+// quantizeGrids maps grid names to their spacing in ticks.
+var quantizeGrids = map[string]uint32{
+	"1/1":  ticksPerQuarter * 4,
+	"1/2":  ticksPerQuarter * 2,
+	"1/4":  ticksPerQuarter,
+	"1/8":  ticksPerQuarter / 2,
+	"1/16": ticksPerQuarter / 4,
+	"1/32": ticksPerQuarter / 8,
+}
+
+// quantizeEvents snaps each note-on to the nearest grid tick and moves its
+// note-off by the same amount, so durations are kept. Note-offs without a
+// matching note-on are snapped on their own. The result is in tick order with
+// releases ahead of strikes at the same tick.
+func quantizeEvents(events []NoteEvent, grid uint32) []NoteEvent {
+	out := append([]NoteEvent(nil), events...)
+	sort.SliceStable(out, func(a, b int) bool { return out[a].Tick < out[b].Tick })
+	snap := func(t uint32) uint32 { return (t + grid/2) / grid * grid }
+
+	// shifts holds, per channel and note, how far each still-sounding
+	// note-on moved, oldest first.
+	shifts := map[[2]byte][]int64{}
+	for i := range out {
+		ev := &out[i]
+		key := [2]byte{ev.Channel, ev.Note}
+		if ev.Type == "noteOn" {
+			snapped := snap(ev.Tick)
+			shifts[key] = append(shifts[key], int64(snapped)-int64(ev.Tick))
+			ev.Tick = snapped
+			continue
+		}
+		if q := shifts[key]; len(q) > 0 {
+			ev.Tick = uint32(int64(ev.Tick) + q[0])
+			shifts[key] = q[1:]
+		} else {
+			ev.Tick = snap(ev.Tick)
+		}
+	}
+	sort.SliceStable(out, func(a, b int) bool {
+		if out[a].Tick != out[b].Tick {
+			return out[a].Tick < out[b].Tick
+		}
+		return out[a].Type == "noteOff" && out[b].Type == "noteOn"
+	})
+	return out
+}
+
+// QuantizeEvents handles POST /api/quantize, snapping a note event list to a
+// rhythmic grid for cleaning up imported or freely played timings.
+func QuantizeEvents(c *gin.Context) {
+	var req QuantizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Grid == "" {
+		req.Grid = "1/16"
+	}
+	grid, ok := quantizeGrids[req.Grid]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown grid: " + req.Grid})
+		return
+	}
+	for i, ev := range req.Events {
+		if ev.Type != "noteOn" && ev.Type != "noteOff" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("events[%d]: type must be \"noteOn\" or \"noteOff\"", i)})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, quantizeEvents(req.Events, grid))
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestMidiNoteName(t *testing.T) {
+	cases := map[byte]string{60: "C4", 61: "C#4", 40: "E2", 0: "C-1", 127: "G9"}
+	for n, want := range cases {
+		if got := midiNoteName(n); got != want {
+			t.Errorf("midiNoteName(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestGetMidiEvents_WholePairs(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":  []string{"C"},
+		"pattern": "whole",
+		"octave":  4,
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi/events", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/midi/events = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var events []NoteEvent
+	if err := json.Unmarshal(w.Body.Bytes(), &events); err != nil {
+		t.Fatalf("decode events: %v", err)
+	}
+	// C major triad: three note-ons at tick 0, three note-offs one bar later.
+	if len(events) != 6 {
+		t.Fatalf("got %d events, want 6: %+v", len(events), events)
+	}
+	on := map[byte]bool{}
+	for _, ev := range events[:3] {
+		if ev.Type != "noteOn" || ev.Tick != 0 {
+			t.Errorf("event %+v, want noteOn at tick 0", ev)
+		}
+		on[ev.Note] = true
+	}
+	for _, ev := range events[3:] {
+		if ev.Type != "noteOff" || ev.Tick != 4*ticksPerQuarter {
+			t.Errorf("event %+v, want noteOff at tick %d", ev, 4*ticksPerQuarter)
+		}
+		if !on[ev.Note] {
+			t.Errorf("noteOff for %s has no matching noteOn", ev.NoteName)
+		}
+	}
+	if events[0].NoteName != "C4" {
+		t.Errorf("first note = %s, want C4", events[0].NoteName)
+	}
+}
+
+func TestGetMidiEvents_PalmMuteStaccato(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":          []string{"E", "A"},
+		"patternSequence": []string{"palm-mute-8th", "whole"},
+		"octave":          3,
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi/events", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/midi/events = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var events []NoteEvent
+	json.Unmarshal(w.Body.Bytes(), &events)
+	bar := uint32(4 * ticksPerQuarter)
+	var staccato int
+	for _, ev := range events {
+		switch {
+		case ev.Type != "noteOn":
+			if ev.Articulation != "" {
+				t.Errorf("note-off %+v is marked %q", ev, ev.Articulation)
+			}
+		case ev.Tick < bar: // palm-mute-8th
+			if ev.Articulation != "staccato" {
+				t.Errorf("palm-muted note at %d marked %q, want staccato", ev.Tick, ev.Articulation)
+			}
+			staccato++
+		default: // whole: the A chord accented on its downbeat
+			if ev.Articulation != "accent" {
+				t.Errorf("A chord note at %d marked %q, want accent", ev.Tick, ev.Articulation)
+			}
+		}
+	}
+	if staccato == 0 {
+		t.Error("no staccato notes in the palm-mute bar")
+	}
+}
+
+func TestGetMidiEvents_AccentOnlyOnDownbeats(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{"chords": []string{"C"}, "pattern": "quarter"})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi/events", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	var events []NoteEvent
+	json.Unmarshal(w.Body.Bytes(), &events)
+	for _, ev := range events {
+		if accented := ev.Articulation == "accent"; ev.Type == "noteOn" && accented != (ev.Tick == 0) {
+			t.Errorf("note-on at %d marked %q", ev.Tick, ev.Articulation)
+		}
+	}
+}
+
+func TestGetMidiEvents_AccentPushedChangesAndBarDownbeats(t *testing.T) {
+	cases := []struct {
+		body map[string]interface{}
+		want []uint32 // ticks of accented note-ons
+	}{
+		// The pushed G lands an eighth before bar 2.
+		{map[string]interface{}{"chords": []string{"C", "G"}, "pattern": "whole", "pushEighths": 1}, []uint32{0, 1680}},
+		// A two-bar chord is accented again on its second downbeat.
+		{map[string]interface{}{"chords": []string{"C:8"}, "pattern": "quarter"}, []uint32{0, 1920}},
+	}
+	r := newRouter()
+	for _, tc := range cases {
+		body, _ := json.Marshal(tc.body)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/midi/events", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		var events []NoteEvent
+		json.Unmarshal(w.Body.Bytes(), &events)
+		var accented []uint32
+		for _, ev := range events {
+			if ev.Articulation == "accent" && (len(accented) == 0 || accented[len(accented)-1] != ev.Tick) {
+				accented = append(accented, ev.Tick)
+			}
+		}
+		if !reflect.DeepEqual(accented, tc.want) {
+			t.Errorf("%v: accents at %v, want %v", tc.body, accented, tc.want)
+		}
+	}
+}
+
+func TestGetMidiEvents_UnknownPattern(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":  []string{"C"},
+		"pattern": "nope",
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/midi/events", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown pattern = %d, want 400", w.Code)
+	}
+}
+
+func TestQuantizeEvents_SnapsToGrid(t *testing.T) {
+	body, _ := json.Marshal(QuantizeRequest{
+		Grid: "1/16",
+		Events: []NoteEvent{
+			{Tick: 10, Type: "noteOn", Note: 60, Velocity: 100},
+			{Tick: 125, Type: "noteOn", Note: 64, Velocity: 100},
+			{Tick: 250, Type: "noteOff", Note: 60},
+			{Tick: 470, Type: "noteOff", Note: 64},
+			{Tick: 700, Type: "noteOff", Note: 67}, // no matching note-on
+		},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/quantize", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/quantize = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var events []NoteEvent
+	if err := json.Unmarshal(w.Body.Bytes(), &events); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	// Note-ons snap to the 120-tick grid; their note-offs keep the durations.
+	want := []struct {
+		tick uint32
+		typ  string
+		note byte
+	}{
+		{0, "noteOn", 60},
+		{120, "noteOn", 64},
+		{240, "noteOff", 60},
+		{465, "noteOff", 64},
+		{720, "noteOff", 67},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, exp := range want {
+		if ev := events[i]; ev.Tick != exp.tick || ev.Type != exp.typ || ev.Note != exp.note {
+			t.Errorf("events[%d] = %s %d at %d, want %s %d at %d", i, ev.Type, ev.Note, ev.Tick, exp.typ, exp.note, exp.tick)
+		}
+	}
+}
+
+func TestQuantizeEvents_UnknownGrid(t *testing.T) {
+	body, _ := json.Marshal(QuantizeRequest{Grid: "1/5", Events: []NoteEvent{{Type: "noteOn", Note: 60}}})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/quantize", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("grid 1/5 = %d, want 400", w.Code)
+	}
+}
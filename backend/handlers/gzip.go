@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gzipMinSize is the smallest JSON body worth compressing; below it the gzip
+// framing outweighs the savings.
+const gzipMinSize = 1024
+
+// gzipWriter compresses the response body when it turns out to be JSON of at
+// least gzipMinSize bytes. The choice is made on the first write, once the
+// handler has set Content-Type; anything else (MIDI files, small bodies)
+// passes through untouched.
+type gzipWriter struct {
+	gin.ResponseWriter
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.decided = true
+		h := w.Header()
+		if strings.HasPrefix(h.Get("Content-Type"), "application/json") && len(b) >= gzipMinSize {
+			h.Set("Content-Encoding", "gzip")
+			h.Del("Content-Length")
+			w.gz = gzip.NewWriter(w.ResponseWriter)
+		}
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Gzip compresses JSON responses to GET requests from clients that send
+// Accept-Encoding: gzip, such as the full chord lists.
+func Gzip() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet || !strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") {
+			c.Next()
+			return
+		}
+		c.Header("Vary", "Accept-Encoding")
+		w := &gzipWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		if w.gz != nil {
+			w.gz.Close()
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"guitartutor/backend/models"
+)
+
+func TestGzip_LargeChordList(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/chords/guitar", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/chords/guitar = %d, want 200", w.Code)
+	}
+	if ce := w.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", ce)
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("body is not gzip: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("decompressing body: %v", err)
+	}
+	var resp models.ChordDiagrams
+	if err := json.Unmarshal(body, &resp); err != nil || len(resp["C"]) == 0 {
+		t.Errorf("decompressed body is not the chord list: %v", err)
+	}
+}
+
+func TestGzip_NotRequested(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/chords/guitar", nil)
+	r.ServeHTTP(w, req)
+
+	if ce := w.Header().Get("Content-Encoding"); ce != "" {
+		t.Errorf("Content-Encoding = %q without Accept-Encoding, want none", ce)
+	}
+}
+
+func TestGzip_MidiUncompressed(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/chords/guitar/C/midi", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	r.ServeHTTP(w, req)
+
+	if ce := w.Header().Get("Content-Encoding"); ce != "" {
+		t.Errorf("MIDI Content-Encoding = %q, want none", ce)
+	}
+	if midi := w.Body.Bytes(); len(midi) < 4 || string(midi[0:4]) != "MThd" {
+		t.Errorf("response is not a valid MIDI file")
+	}
+}
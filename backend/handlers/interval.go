@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IntervalMidiRequest is the JSON body for POST /api/interval-midi.
+type IntervalMidiRequest struct {
+	Root     string `json:"root"     binding:"required"` // key name with octave, e.g. "C4"
+	Interval string `json:"interval" binding:"required"` // e.g. "P5", "m3", "TT"
+	Tempo    int    `json:"tempo"`                       // BPM (default 90)
+}
+
+// intervalSemitones maps interval names to their size in semitones.
+var intervalSemitones = map[string]int{
+	"P1": 0, "m2": 1, "M2": 2, "m3": 3, "M3": 4, "P4": 5,
+	"TT": 6, "A4": 6, "d5": 6, "P5": 7, "m6": 8, "M6": 9,
+	"m7": 10, "M7": 11, "P8": 12,
+}
+
+// buildIntervalTrack plays root then top as quarter notes (melodic), then
+// both together as a half note (harmonic). A unison is struck once in the
+// harmonic part, as one channel can't sound the same pitch twice.
+func buildIntervalTrack(root, top byte, tempo int) []byte {
+	const q = ticksPerQuarter
+	var trk []byte
+	trk = append(trk, tempoEvent(tempo)...)
+	trk = append(trk, noteOnEvent(0, 0, root, 100)...)
+	trk = append(trk, noteOffEvent(q, 0, root, 0)...)
+	trk = append(trk, noteOnEvent(0, 0, top, 100)...)
+	trk = append(trk, noteOffEvent(q, 0, top, 0)...)
+	if top == root {
+		trk = append(trk, noteOnEvent(0, 0, root, 100)...)
+		trk = append(trk, noteOffEvent(2*q, 0, root, 0)...)
+		return append(trk, endOfTrack()...)
+	}
+	trk = append(trk, noteOnEvent(0, 0, root, 100)...)
+	trk = append(trk, noteOnEvent(0, 0, top, 100)...)
+	trk = append(trk, noteOffEvent(2*q, 0, root, 0)...)
+	trk = append(trk, noteOffEvent(0, 0, top, 0)...)
+	trk = append(trk, endOfTrack()...)
+	return trk
+}
+
+// GenerateIntervalMidi handles POST /api/interval-midi, rendering an interval
+// for ear training: melodically, then harmonically.
+func GenerateIntervalMidi(c *gin.Context) {
+	var req IntervalMidiRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	root := keyNameToMidi(req.Root)
+	if root == -1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unrecognised root: " + req.Root})
+		return
+	}
+	semitones, ok := intervalSemitones[req.Interval]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown interval: " + req.Interval})
+		return
+	}
+	top := root + semitones
+	if top > 127 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval exceeds the MIDI note range"})
+		return
+	}
+	if req.Tempo <= 0 || req.Tempo > 300 {
+		req.Tempo = 90
+	}
+
+	midi := writeSMF([][]byte{buildIntervalTrack(byte(root), byte(top), req.Tempo)})
+
+	requestLogger(c).Info("interval midi generated",
+		"root", req.Root,
+		"interval", req.Interval,
+		"bytes", len(midi),
+	)
+
+	c.Header("Content-Disposition", "attachment; filename=\"interval.mid\"")
+	c.Data(http.StatusOK, "audio/midi", midi)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGenerateIntervalMidi_PerfectFifth(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{"root": "C4", "interval": "P5", "tempo": 80})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/interval-midi", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/interval-midi = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	midi := w.Body.Bytes()
+	if len(midi) < 4 || string(midi[0:4]) != "MThd" {
+		t.Fatalf("response is not a valid MIDI file")
+	}
+
+	var ons []byte
+	var harmonicOnsets int
+	for _, ev := range trackEvents(t, midi) {
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+			ons = append(ons, ev.data1)
+			if len(ons) > 2 && ev.delta == 0 {
+				harmonicOnsets++
+			}
+		}
+	}
+	// melodic C4, G4 then harmonic C4+G4
+	if want := []byte{60, 67, 60, 67}; !bytes.Equal(ons, want) {
+		t.Errorf("note-ons = %v, want %v", ons, want)
+	}
+	if harmonicOnsets != 2 {
+		t.Errorf("harmonic notes not struck together")
+	}
+}
+
+func TestBuildIntervalTrack_UnisonStruckOnce(t *testing.T) {
+	events := decodeEvents(buildIntervalTrack(60, 60, 90))
+	var ons, offs int
+	for _, ev := range events {
+		switch {
+		case ev.status&0xF0 == 0x90 && ev.data2 > 0:
+			ons++
+		case ev.status&0xF0 == 0x80:
+			offs++
+		}
+	}
+	// melodic C4, C4 then one harmonic C4
+	if ons != 3 || offs != 3 {
+		t.Errorf("unison has %d note-ons and %d note-offs, want 3 of each", ons, offs)
+	}
+	if got, want := sumDeltas(events), uint32(4*ticksPerQuarter); got != want {
+		t.Errorf("length = %d ticks, want %d", got, want)
+	}
+}
+
+func TestGenerateIntervalMidi_BadInput(t *testing.T) {
+	cases := []map[string]interface{}{
+		{"root": "H4", "interval": "P5"},
+		{"root": "C4", "interval": "P9"},
+		{"root": "G9", "interval": "P8"},
+	}
+	r := newRouter()
+	for _, c := range cases {
+		body, _ := json.Marshal(c)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/interval-midi", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%v: got %d, want 400", c, w.Code)
+		}
+	}
+}
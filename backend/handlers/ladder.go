@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLadderSteps caps how many tempos one POST /api/tempo-ladder may request.
+const maxLadderSteps = 16
+
+// TempoLadderRequest is the JSON body for POST /api/tempo-ladder.
+type TempoLadderRequest struct {
+	Chords  []string `json:"chords"  binding:"required"`
+	Pattern string   `json:"pattern"`                    // default "quarter"; "custom" and "strum-dsl" are not supported
+	Tempos  []int    `json:"tempos"  binding:"required"` // BPM of each pass, in play order, e.g. [60,70,80]
+	Octave  int      `json:"octave"`                     // base octave (default 4)
+	Beats   int      `json:"beats"`                      // beats per chord (default 4)
+	Swing   int      `json:"swing"`                      // swing ratio 50–75 for the passes and the count-in; 0 = straight
+}
+
+// countIn returns one bar of metronome clicks on the drum channel, a bell on
+// the downbeat, lasting exactly beats quarter notes. With swing set, a soft
+// click on each swung off-beat sets up the feel before the music starts.
+func countIn(beats, swing int) []byte {
+	hits := []drumHit{{0, gmMetronomeBell, 110}}
+	for beat := 0; beat < beats; beat++ {
+		start := uint32(beat * ticksPerQuarter)
+		if beat > 0 {
+			hits = append(hits, drumHit{start, gmMetronomeClick, 100})
+		}
+		if swing > 0 {
+			hits = append(hits, drumHit{start + uint32(ticksPerQuarter*swing/100), gmMetronomeClick, 60})
+		}
+	}
+	return encodeDrumHits(hits, uint32(beats*ticksPerQuarter), 0)
+}
+
+// buildTempoLadder plays the progression once per tempo, each pass opening
+// with its tempo event and a one-bar count-in at that tempo.
+func buildTempoLadder(req TempoLadderRequest) []byte {
+	eot := endOfTrack()
+	var trk []byte
+	for _, bpm := range req.Tempos {
+		pass := buildTrack(MidiRequest{
+			Chords:  req.Chords,
+			Tempo:   bpm,
+			Pattern: req.Pattern,
+			Octave:  req.Octave,
+			Beats:   req.Beats,
+			Swing:   req.Swing,
+		})
+		// buildTrack opens with the tempo event; the count-in goes straight after it.
+		tempo := tempoEvent(bpm)
+		trk = append(trk, tempo...)
+		trk = append(trk, countIn(req.Beats, req.Swing)...)
+		trk = append(trk, bytes.TrimSuffix(pass[len(tempo):], eot)...)
+	}
+	trk = append(trk, eot...)
+	return writeSMF([][]byte{trk})
+}
+
+// GenerateTempoLadder handles POST /api/tempo-ladder, rendering a practice
+// file that repeats the progression at each requested tempo in turn.
+func GenerateTempoLadder(c *gin.Context) {
+	var req TempoLadderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Chords) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "chords must not be empty"})
+		return
+	}
+	if len(req.Tempos) == 0 || len(req.Tempos) > maxLadderSteps {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("tempos must list 1–%d tempos", maxLadderSteps)})
+		return
+	}
+	for i, bpm := range req.Tempos {
+		if bpm <= 0 || bpm > 300 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("tempos[%d] must be in range 1–300", i)})
+			return
+		}
+	}
+
+	// Apply defaults
+	if req.Pattern == "" {
+		req.Pattern = "quarter"
+	}
+	if !validPatterns[req.Pattern] || req.Pattern == "custom" || req.Pattern == "strum-dsl" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown pattern: " + req.Pattern})
+		return
+	}
+	if req.Swing != 0 && (req.Swing < 50 || req.Swing > 75) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "swing must be 0 or in range 50–75"})
+		return
+	}
+	if req.Octave < 0 || req.Octave > 8 {
+		req.Octave = 4
+	}
+	if req.Beats <= 0 {
+		req.Beats = 4
+	}
+
+	midi := buildTempoLadder(req)
+
+	requestLogger(c).Info("tempo ladder generated",
+		"steps", len(req.Tempos),
+		"chords", len(req.Chords),
+		"bytes", len(midi),
+	)
+
+	c.Header("Content-Disposition", "attachment; filename=\"tempo-ladder.mid\"")
+	c.Data(http.StatusOK, "audio/midi", midi)
+}
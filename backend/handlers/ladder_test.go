@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGenerateTempoLadder_AscendingTempos(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords":  []string{"C", "G"},
+		"pattern": "quarter",
+		"tempos":  []int{60, 70, 80},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/tempo-ladder", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/tempo-ladder = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	chunks := trackChunks(t, w.Body.Bytes())
+	if len(chunks) != 1 {
+		t.Fatalf("got %d tracks, want 1", len(chunks))
+	}
+
+	// Tempo meta events carry microseconds per quarter: slower first.
+	var usPerQuarter []int
+	for _, ev := range parseTrack(chunks[0]) {
+		if ev.status == 0xFF && ev.data1 == 0x51 {
+			usPerQuarter = append(usPerQuarter, int(ev.meta[0])<<16|int(ev.meta[1])<<8|int(ev.meta[2]))
+		}
+	}
+	want := []int{60000000 / 60, 60000000 / 70, 60000000 / 80}
+	if len(usPerQuarter) != len(want) {
+		t.Fatalf("got %d tempo events, want %d", len(usPerQuarter), len(want))
+	}
+	for i := range want {
+		if usPerQuarter[i] != want[i] {
+			t.Errorf("tempo %d = %d µs/quarter, want %d", i, usPerQuarter[i], want[i])
+		}
+	}
+}
+
+func TestBuildTempoLadder_CountInPerPass(t *testing.T) {
+	midi := buildTempoLadder(TempoLadderRequest{
+		Chords:  []string{"Am"},
+		Pattern: "whole",
+		Tempos:  []int{90, 100},
+		Octave:  4,
+		Beats:   4,
+	})
+	events := trackEvents(t, midi)
+	var bells int
+	for _, ev := range events {
+		if ev.status == 0x90|drumChannel && ev.data1 == gmMetronomeBell {
+			bells++
+		}
+	}
+	if bells != 2 {
+		t.Errorf("got %d count-in bars, want one per tempo (2)", bells)
+	}
+	// Each pass: a one-bar count-in plus one bar of Am.
+	if got, want := sumDeltas(events), uint32(2*2*4*ticksPerQuarter); got != want {
+		t.Errorf("ladder length = %d ticks, want %d", got, want)
+	}
+}
+
+func TestGenerateTempoLadder_Invalid(t *testing.T) {
+	cases := []map[string]interface{}{
+		{"chords": []string{"C"}, "tempos": []int{}},
+		{"chords": []string{"C"}, "tempos": []int{60, 400}},
+		{"chords": []string{}, "tempos": []int{60}},
+		{"chords": []string{"C"}, "tempos": []int{60}, "pattern": "custom"},
+	}
+	r := newRouter()
+	for _, c := range cases {
+		body, _ := json.Marshal(c)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/tempo-ladder", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%v: got %d, want 400", c, w.Code)
+		}
+	}
+}
+
+func TestBuildTempoLadder_SwungCountIn(t *testing.T) {
+	midi := buildTempoLadder(TempoLadderRequest{
+		Chords:  []string{"C"},
+		Pattern: "rock-8th",
+		Tempos:  []int{120},
+		Octave:  4,
+		Beats:   4,
+		Swing:   66,
+	})
+	var clicks []uint32
+	for _, ev := range parseTrack(trackChunks(t, midi)[0]) {
+		if ev.status == 0x90|drumChannel && ev.data2 > 0 {
+			clicks = append(clicks, ev.tick)
+		}
+	}
+	// Bell/click on each beat and a soft click 66% of the way through it.
+	long := uint32(ticksPerQuarter * 66 / 100)
+	if len(clicks) != 8 {
+		t.Fatalf("got %d count-in clicks, want 8: %v", len(clicks), clicks)
+	}
+	for i := 1; i < len(clicks); i++ {
+		want := long
+		if i%2 == 0 {
+			want = ticksPerQuarter - long
+		}
+		if gap := clicks[i] - clicks[i-1]; gap != want {
+			t.Errorf("click gap %d = %d ticks, want %d", i, gap, want)
+		}
+	}
+}
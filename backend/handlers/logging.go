@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the per-request correlation ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the gin context key the request ID is stored under.
+const requestIDKey = "requestID"
+
+// maxRequestIDLen bounds client-supplied IDs so they can't bloat log lines.
+const maxRequestIDLen = 64
+
+// RequestID assigns every request an ID, reusing a client-supplied X-Request-ID
+// when present, and echoes it in the response header. Once the handler chain
+// returns it logs the outcome (status and latency) under the same ID.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLen {
+			id = newRequestID()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+
+		start := time.Now()
+		c.Next()
+
+		requestLogger(c).Info("request completed",
+			"status", c.Writer.Status(),
+			"latency_ms", time.Since(start).Milliseconds(),
+		)
+	}
+}
+
+// newRequestID returns a random 16-character hex ID.
+func newRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// requestLogger returns the default slog logger annotated with the request ID
+// and the matched endpoint, so every line a handler writes can be correlated.
+func requestLogger(c *gin.Context) *slog.Logger {
+	endpoint := c.FullPath()
+	if endpoint == "" {
+		endpoint = c.Request.URL.Path
+	}
+	return slog.Default().With(
+		"request_id", c.GetString(requestIDKey),
+		"endpoint", c.Request.Method+" "+endpoint,
+	)
+}
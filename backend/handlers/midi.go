@@ -3,27 +3,191 @@ package handlers
 import (
 	"bytes"
 	"encoding/binary"
-	"log"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+
+	"guitartutor/backend/models"
 )
 
 // ── MIDI request / chord-quality tables ──────────────────────────────────────
 
 // MidiRequest is the JSON body for POST /api/midi.
 type MidiRequest struct {
-	Chords   []string   `json:"chords"   binding:"required"` // e.g. ["C","Am","F","G"]
-	Tempo    int        `json:"tempo"`                       // BPM (default 120)
-	Pattern  string     `json:"pattern"`                     // "whole","half","quarter","arpeggio-up","arpeggio-down","boom-chick","pop-strum","travis-picking","alberti-bass","triplet-arpeggio","pop-stabs","bossa-nova","reggae-skank","funk-16th","jazz-swing","rock-8th","let-it-be","stand-by-me","creep-arpeggio","twist-and-shout","blues-shuffle","sweet-home-alabama","stairway-arpeggio","hotel-california","wonderwall-strum","blackbird-pick","palm-mute-8th","off-beat-8th","country-alt-bass","pima-arpeggio","four-on-the-floor"
-	Octave   int        `json:"octave"`                      // base octave 2–6 (default 4)
-	Beats    int        `json:"beats"`                       // beats per chord (default 4)
-	Frets    [][]string `json:"frets"`                       // per-chord fret positions (e.g. ["x","3","2","0","1","0"])
-	OpenMidi []int      `json:"openMidi"`                    // open-string MIDI notes for the current instrument
+	Chords            []string      `json:"chords"`            // e.g. ["C","Am","F","G"], or "C:2" for a 2-beat chord; empty = tempo-only file
+	Tempo             int           `json:"tempo"`             // BPM (default 120)
+	Pattern           string        `json:"pattern"`           // "whole","half","quarter","arpeggio-up","arpeggio-down","boom-chick","pop-strum","travis-picking","alberti-bass","triplet-arpeggio","pop-stabs","bossa-nova","reggae-skank","funk-16th","jazz-swing","rock-8th","let-it-be","stand-by-me","creep-arpeggio","twist-and-shout","blues-shuffle","sweet-home-alabama","stairway-arpeggio","hotel-california","wonderwall-strum","blackbird-pick","palm-mute-8th","off-beat-8th","country-alt-bass","pima-arpeggio","four-on-the-floor","walking-bass","bossa-2bar","scale-run","arpeggio","custom","strum-dsl"
+	Octave            int           `json:"octave"`            // base octave 2–6 (default 4)
+	Beats             int           `json:"beats"`             // beats per chord (default 4)
+	Frets             [][]string    `json:"frets"`             // per-chord fret positions (e.g. ["x","3","2","0","1","0"])
+	OpenMidi          []int         `json:"openMidi"`          // open-string MIDI notes for the current instrument
+	Subdivision       int           `json:"subdivision"`       // notes per beat for the "arpeggio" pattern, 1–16 (default 2)
+	Rhythm            []string      `json:"rhythm"`            // note values for the "custom" pattern, e.g. ["q","e","e","h"]; must fill the bar
+	IntroStrum        bool          `json:"introStrum"`        // open with a slow roll across all open strings (needs openMidi)
+	ReleaseVelocity   byte          `json:"releaseVelocity"`   // note-off velocity 0–127 (default 0)
+	PatternSequence   []string      `json:"patternSequence"`   // per-chord patterns, cycled over the chords; overrides pattern
+	SwingDelay        int           `json:"swingDelay"`        // ticks to lay back off-beat hits in jazz-swing/blues-shuffle, 0–120
+	MaxNotes          int           `json:"maxNotes"`          // cap on simultaneous chord tones; 0 = unlimited
+	ArpSequence       []int         `json:"arpSequence"`       // note order for the "arpeggio" pattern, e.g. [0,2,1,2]; -1 = top note
+	PushEighths       int           `json:"pushEighths"`       // land each chord change this many eighths before the bar line
+	Drums             bool          `json:"drums"`             // add a basic rock beat on the GM drum channel (separate track)
+	FillLastBar       bool          `json:"fillLastBar"`       // replace the last bar's beat with a tom/snare fill (needs drums)
+	OpenMidiOverrides map[int]int   `json:"openMidiOverrides"` // string index → replacement open-string MIDI note, e.g. {"0":38} for drop D
+	Capo              int           `json:"capo"`              // fret mode: capo fret (0–12) raising the open strings; frets stay relative to the capo
+	CapoStrings       []int         `json:"capoStrings"`       // string indices the capo covers, for a partial capo; empty = all strings
+	Format            string        `json:"format"`            // "midi" (default, binary file) or "json" (base64 file + warnings)
+	ClickSubdivision  string        `json:"clickSubdivision"`  // "quarter" or "eighth" adds a metronome track; empty = none
+	RangeLow          int           `json:"rangeLow"`          // fold chord-quality voicings into RangeLow–RangeHigh (MIDI notes); 0,0 = off
+	RangeHigh         int           `json:"rangeHigh"`         // inclusive upper bound of the fold range
+	Repeat            int           `json:"repeat"`            // number of passes through the chords (default 1)
+	RepeatTranspose   int           `json:"repeatTranspose"`   // semitones to shift each successive pass, -12–12
+	Instrument        string        `json:"instrument"`        // instrument key; chord-quality voicings fold into its MinMidi–MaxMidi
+	FinalHold         bool          `json:"finalHold"`         // sustain the last chord as one block chord, whatever the pattern
+	TempoMap          []TempoChange `json:"tempoMap"`          // tempo changes at chord boundaries, applied on every pass
+	OpenCents         []int         `json:"openCents"`         // per-string tuning offset in cents (−100–100), aligned with openMidi; rendered as pitch bend (needs perStringChannel)
+	StrumDSL          string        `json:"strumDSL"`          // eighth-note strums for the "strum-dsl" pattern: D = down, U = up, - = rest, e.g. "D-DU-UDU"
+	PerStringChannel  bool          `json:"perStringChannel"`  // fret mode: put each string's notes on its own channel (skipping drums), for hex-pickup synths
+	StartTick         uint32        `json:"startTick"`         // silence before the first event, in ticks (480 per quarter), for stitching after other material
+	Keys              [][]string    `json:"keys"`              // per-chord piano keys (e.g. ["C4","E4","G4"]), used when a chord has no frets
+	TieRepeats        bool          `json:"tieRepeats"`        // hold a "whole" chord across consecutive identical chords instead of re-striking
+	AttackEachBar     bool          `json:"attackEachBar"`     // re-strike a "whole" chord longer than a bar on every bar line; excludes tieRepeats
+	CleanBass         bool          `json:"cleanBass"`         // raise chord-quality notes below E3 that sit a third or less above the note beneath an octave
+	Feel              string        `json:"feel"`              // "normal" (default), "double" (pattern at twice the density) or "half"; chord lengths and BPM are unchanged
+	Voicing           string        `json:"voicing"`           // chord-quality seventh chords: "close" (default), "drop2" or "drop3"; triads are unchanged
+	Swing             int           `json:"swing"`             // swing ratio 50–75 (% of the beat the on-beat eighth takes) for straight-eighth patterns; 0 = straight
+	MaxRootMidi       byte          `json:"maxRootMidi"`       // transpose everything down so the first chord's root is at most this MIDI note; 0 = off
+	DownSpread        uint32        `json:"downSpread"`        // ticks between strings on down-strums in pop-strum, twist-and-shout and strum-dsl, 0–30
+	UpSpread          uint32        `json:"upSpread"`          // ticks between strings on up-strums, 0–30 (default: downSpread)
+	Backbeat          string        `json:"backbeat"`          // drum name on beats 2 and 4 of the drum groove, e.g. "rimshot" (default "snare")
+	ClickDropAfter    int           `json:"clickDropAfter"`    // silence the click after this many passes, for internal-clock practice; 0 = click throughout
+	Rubato            float64       `json:"rubato"`            // depth of a gentle sinusoidal tempo swell across bars, 0–0.25 (fraction of the tempo); 0 = strict time
+	GraceNotes        bool          `json:"graceNotes"`        // slide into each chord change from a soft note a semitone below the next chord's bass
+	MarkerTrack       bool          `json:"markerTrack"`       // add a track of marker meta events naming each chord change, for players with a markers lane
+	Stems             bool          `json:"stems"`             // format "json" only: also return separate chord and bass-root files for play-along
+
+	// CustomIntervals adds chord qualities for this request, or replaces
+	// qualityIntervals entries, e.g. {"oct": [0, 12]} voices "Coct" as C4 C5.
+	CustomIntervals map[string][]int `json:"customIntervals"`
+
+	// chordBeats holds per-chord lengths parsed from "chord:beats" entries
+	// (e.g. "C:2"); 0 or missing means Beats.
+	chordBeats []int
+
+	// rootShift is the semitone shift MaxRootMidi applied to every chord.
+	rootShift int
+
+	// bassOnly renders each chord as its lowest note an octave down, for
+	// the bass stem.
+	bassOnly bool
+
+	// clamped notes each out-of-range value bindMidiRequest replaced with
+	// its default, e.g. "tempo 400 out of range, used 120".
+	clamped []string
+}
+
+// TempoChange switches the tempo to BPM when the chord at ChordIndex starts.
+type TempoChange struct {
+	ChordIndex int `json:"chordIndex"`
+	BPM        int `json:"bpm"`
+}
+
+// MidiJSONResponse is returned by POST /api/midi when format is "json": the
+// file itself plus diagnostics about how the request was rendered.
+type MidiJSONResponse struct {
+	Midi       []byte           `json:"midi"` // base64-encoded SMF
+	Warnings   []string         `json:"warnings"`
+	Fingerings []ChordFingering `json:"fingerings,omitempty"` // per chord, when instrument and frets are given
+	RootShift  int              `json:"rootShift,omitempty"`  // semitones applied to fit maxRootMidi (≤ 0)
+	Applied    AppliedSettings  `json:"appliedSettings"`
+	Stems      *MidiStems       `json:"stems,omitempty"` // when the request sets stems
+}
+
+// MidiStems holds the play-along stems of a request, each a single-track SMF
+// without drums or click: the chord part, and the bass note of each chord
+// held for its full length.
+type MidiStems struct {
+	Chord []byte `json:"chord"` // base64-encoded
+	Bass  []byte `json:"bass"`  // base64-encoded
+}
+
+// AppliedSettings echoes the settings a request was rendered with once
+// defaults were filled in, so clients can see what the server actually did.
+type AppliedSettings struct {
+	Tempo           int      `json:"tempo"`
+	Pattern         string   `json:"pattern"`
+	Swing           int      `json:"swing"` // 0 = straight
+	Beats           int      `json:"beats"`
+	Octave          int      `json:"octave"`
+	PPQ             int      `json:"ppq"` // ticks per quarter note
+	ReleaseVelocity byte     `json:"releaseVelocity"`
+	Clamped         []string `json:"clamped"` // out-of-range values replaced by defaults
+}
+
+// appliedSettings reports the resolved settings of a bound request.
+func appliedSettings(req MidiRequest) AppliedSettings {
+	clamped := req.clamped
+	if clamped == nil {
+		clamped = []string{}
+	}
+	return AppliedSettings{
+		Tempo:           req.Tempo,
+		Pattern:         req.Pattern,
+		Swing:           req.Swing,
+		Beats:           req.Beats,
+		Octave:          req.Octave,
+		PPQ:             ticksPerQuarter,
+		ReleaseVelocity: req.ReleaseVelocity,
+		Clamped:         clamped,
+	}
+}
+
+// ChordFingering ties a chord's frets to the fingers of the diagram variant
+// they came from. Fingers is aligned with Frets ("" for open or muted strings)
+// and null when no variant of the chord matches the frets.
+type ChordFingering struct {
+	Chord   string   `json:"chord"`
+	Frets   []string `json:"frets"`
+	Fingers []string `json:"fingers"`
+}
+
+// chordFingerings looks up, for each chord with frets, the instrument's diagram
+// variant with exactly those frets and returns its fingering.
+func chordFingerings(req MidiRequest) []ChordFingering {
+	if req.Instrument == "" || len(req.Frets) == 0 {
+		return nil
+	}
+	diagrams, err := loadChordDiagrams(req.Instrument)
+	if err != nil {
+		return nil
+	}
+	var fingerings []ChordFingering
+	for ci, chord := range req.Chords {
+		if ci >= len(req.Frets) {
+			break
+		}
+		f := ChordFingering{Chord: chord, Frets: req.Frets[ci]}
+		for _, v := range diagrams[normalizeChordName(chord)] {
+			if slices.Equal(v.Frets, req.Frets[ci]) {
+				f.Fingers = v.Fingers
+				break
+			}
+		}
+		fingerings = append(fingerings, f)
+	}
+	return fingerings
 }
 
+// minClearNotes is the fewest sounding notes a fret voicing needs before it
+// is flagged as too thin to read as a chord.
+const minClearNotes = 3
+
 // qualityIntervals maps the suffix after the root to semitone intervals.
 var qualityIntervals = map[string][]int{
 	"":      {0, 4, 7},
@@ -31,6 +195,7 @@ var qualityIntervals = map[string][]int{
 	"7":     {0, 4, 7, 10},
 	"maj7":  {0, 4, 7, 11},
 	"m7":    {0, 3, 7, 10},
+	"m7b5":  {0, 3, 6, 10},
 	"dim":   {0, 3, 6},
 	"aug":   {0, 4, 8},
 	"sus2":  {0, 2, 7},
@@ -39,6 +204,10 @@ var qualityIntervals = map[string][]int{
 	"m6":    {0, 3, 7, 9},
 	"add9":  {0, 4, 7, 14},
 	"madd9": {0, 3, 7, 14},
+
+	// Thirdless voicings: the power chord and a stack of fourths.
+	"5":       {0, 7},
+	"quartal": {0, 5, 10},
 }
 
 // fretsToMidi converts fret positions + open-string MIDI tuning to a sorted,
@@ -72,17 +241,215 @@ func fretsToMidi(frets []string, openMidi []int) []byte {
 	return result
 }
 
+// chordWarnings reports fret voicings that sound fewer than minClearNotes
+// distinct pitches (usually from heavy muting). Chords without frets are skipped.
+func chordWarnings(req MidiRequest) []string {
+	warnings := []string{}
+	if len(req.OpenMidi) == 0 {
+		return warnings
+	}
+	openMidi := tuning(req)
+	for ci, chordName := range req.Chords {
+		if ci >= len(req.Frets) {
+			break
+		}
+		switch n := len(fretsToMidi(req.Frets[ci], openMidi)); {
+		case n == 0:
+			warnings = append(warnings, fmt.Sprintf("chord %d (%s): no sounding strings; fell back to chord-quality notes", ci+1, chordName))
+		case n < minClearNotes:
+			warnings = append(warnings, fmt.Sprintf("chord %d (%s): only %d sounding notes", ci+1, chordName, n))
+		}
+	}
+	return warnings
+}
+
+// swingPatterns are the straight-eighth patterns Swing applies to. Patterns
+// that define their own feel (bossa-nova, jazz-swing, funk-16th, …) ignore it;
+// jazz-swing and blues-shuffle take SwingDelay instead.
+var swingPatterns = map[string]bool{
+	"rock-8th":      true,
+	"pop-strum":     true,
+	"off-beat-8th":  true,
+	"palm-mute-8th": true,
+}
+
+// swingWarnings notes each pattern in the request that ignores Swing.
+func swingWarnings(req MidiRequest) []string {
+	warnings := []string{}
+	if req.Swing == 0 {
+		return warnings
+	}
+	seen := map[string]bool{}
+	for _, p := range append([]string{req.Pattern}, req.PatternSequence...) {
+		if !swingPatterns[p] && !seen[p] {
+			seen[p] = true
+			warnings = append(warnings, fmt.Sprintf("swing ignored for pattern %q, which defines its own feel", p))
+		}
+	}
+	return warnings
+}
+
+// applySwing retimes a rendered chord slot so each beat's first eighth takes
+// swing% of the beat and the second eighth the rest. Times inside each half of
+// the beat are stretched proportionally, so event order is preserved.
+func applySwing(slot []byte, beatTicks uint32, swing int) []byte {
+	long := beatTicks * uint32(swing) / 100
+	half := beatTicks / 2
+	swung := func(t uint32) uint32 {
+		beat, pos := t/beatTicks*beatTicks, t%beatTicks
+		if pos <= half {
+			return beat + pos*long/half
+		}
+		return beat + long + (pos-half)*(beatTicks-long)/half
+	}
+	var out []byte
+	var now, emitted uint32
+	for i := 0; i < len(slot); {
+		delta, next := readVarLen(slot, i)
+		if next+3 > len(slot) {
+			break
+		}
+		now += delta
+		at := swung(now)
+		out = append(out, varLen(at-emitted)...)
+		out = append(out, slot[next:next+3]...)
+		emitted = at
+		i = next + 3
+	}
+	return out
+}
+
+// scaleSlot retimes a rendered chord slot by num/den, stretching or squeezing
+// every event's position within the slot; event order is preserved.
+func scaleSlot(slot []byte, num, den uint32) []byte {
+	var out []byte
+	var now, emitted uint32
+	for i := 0; i < len(slot); {
+		delta, next := readVarLen(slot, i)
+		if next+3 > len(slot) {
+			break
+		}
+		now += delta
+		at := now * num / den
+		out = append(out, varLen(at-emitted)...)
+		out = append(out, slot[next:next+3]...)
+		emitted = at
+		i = next + 3
+	}
+	return out
+}
+
+// applyFeel re-renders a chord slot in double time (the pattern played twice
+// as fast, twice over) or half time (played half as fast, cut to the slot),
+// so the chord keeps its length.
+func applyFeel(slot []byte, feel string, chordTicks uint32, offVel byte) []byte {
+	switch feel {
+	case "double":
+		fast := scaleSlot(slot, 1, 2)
+		return append(fast, fast...)
+	case "half":
+		return truncateSlot(scaleSlot(slot, 2, 1), chordTicks, offVel)
+	}
+	return slot
+}
+
+// applyTuningOverrides returns a copy of openMidi with individual strings
+// retuned, e.g. {0: 38} drops a guitar's low E to D. Indices outside the
+// tuning are ignored.
+func applyTuningOverrides(openMidi []int, overrides map[int]int) []int {
+	if len(overrides) == 0 {
+		return openMidi
+	}
+	tuned := make([]int, len(openMidi))
+	copy(tuned, openMidi)
+	for i, m := range overrides {
+		if i >= 0 && i < len(tuned) {
+			tuned[i] = m
+		}
+	}
+	return tuned
+}
+
+// tuning returns the open strings a fret-mode request actually plays:
+// OpenMidi with its overrides applied, then raised by Capo on CapoStrings
+// (every string when CapoStrings is empty).
+func tuning(req MidiRequest) []int {
+	openMidi := applyTuningOverrides(req.OpenMidi, req.OpenMidiOverrides)
+	if req.Capo == 0 {
+		return openMidi
+	}
+	capoed := slices.Clone(openMidi)
+	for i := range capoed {
+		if len(req.CapoStrings) == 0 || slices.Contains(req.CapoStrings, i) {
+			capoed[i] += req.Capo
+		}
+	}
+	return capoed
+}
+
+// keyNameToMidi parses a key name with octave ("C4", "F#3", "Bb2") into a MIDI
+// note number (C4 = 60), or -1 if it isn't one.
+func keyNameToMidi(name string) int {
+	pc := chordRootIndex(name)
+	if pc == -1 {
+		return -1
+	}
+	octave, err := strconv.Atoi(chordSuffix(name))
+	if err != nil {
+		return -1
+	}
+	if n := (octave+1)*12 + pc; n >= 0 && n <= 127 {
+		return n
+	}
+	return -1
+}
+
+// keysToMidi converts piano key names to a sorted, deduplicated slice of MIDI
+// note bytes. Names that don't parse are skipped.
+func keysToMidi(keys []string) []byte {
+	var pitches []int
+	for _, k := range keys {
+		if n := keyNameToMidi(k); n != -1 {
+			pitches = append(pitches, n)
+		}
+	}
+	sort.Ints(pitches)
+	var result []byte
+	for i, p := range pitches {
+		if i == 0 || p != pitches[i-1] {
+			result = append(result, byte(p))
+		}
+	}
+	return result
+}
+
 // chordToMidi resolves a chord name (e.g. "C#m7") to a slice of MIDI note numbers.
 func chordToMidi(chord string, baseOctave int) []byte {
+	return voiceChord(chord, baseOctave, nil)
+}
+
+// chordIntervals returns the semitone intervals of chord's quality: the
+// request's custom entry if it has one, else qualityIntervals, else major.
+func chordIntervals(chord string, custom map[string][]int) []int {
+	suffix := chordSuffix(chord)
+	if intervals, ok := custom[suffix]; ok {
+		return intervals
+	}
+	if intervals, ok := qualityIntervals[suffix]; ok {
+		return intervals
+	}
+	return qualityIntervals[""] // fallback to major
+}
+
+// voiceChord is chordToMidi with a request's CustomIntervals, which take
+// precedence over qualityIntervals for the suffixes they define. The notes
+// come back sorted and deduplicated whatever order the intervals are in.
+func voiceChord(chord string, baseOctave int, custom map[string][]int) []byte {
 	root := chordRootIndex(chord)
 	if root == -1 {
 		root = 0
 	}
-	suffix := chordSuffix(chord)
-	intervals, ok := qualityIntervals[suffix]
-	if !ok {
-		intervals = qualityIntervals[""] // fallback to major
-	}
+	intervals := chordIntervals(chord, custom)
 
 	baseMidi := 12*(baseOctave+1) + root // C4 = 60 when baseOctave=4
 	var notes []byte
@@ -92,7 +459,89 @@ func chordToMidi(chord string, baseOctave int) []byte {
 			notes = append(notes, byte(p))
 		}
 	}
-	return notes
+	slices.Sort(notes)
+	return slices.Compact(notes)
+}
+
+// muddyBelow is the MIDI note (E3) under which thirds and seconds sound muddy.
+const muddyBelow = 52
+
+// cleanBass raises an octave each note below muddyBelow that sits a third or
+// less above the note beneath it, keeping the bass note where it is. The
+// result is sorted and deduplicated.
+func cleanBass(notes []byte) []byte {
+	sorted := slices.Clone(notes)
+	slices.Sort(sorted)
+	if len(sorted) < 2 {
+		return sorted
+	}
+	out := []byte{sorted[0]}
+	below := sorted[0] // highest note left in place so far
+	for _, n := range sorted[1:] {
+		if n < muddyBelow && n-below <= 4 && n+12 <= 127 {
+			out = append(out, n+12)
+			continue
+		}
+		out = append(out, n)
+		below = n
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// dropVoicing lowers the drop-th voice from the top of a four-note chord an
+// octave, turning close position into drop 2 (drop = 2) or drop 3 (drop = 3).
+// Other chord sizes, and voices that would fall below note 0, pass through.
+// The result is sorted.
+func dropVoicing(notes []byte, drop int) []byte {
+	sorted := slices.Clone(notes)
+	slices.Sort(sorted)
+	i := len(sorted) - drop
+	if len(sorted) != 4 || sorted[i] < 12 {
+		return sorted
+	}
+	sorted[i] -= 12
+	slices.Sort(sorted)
+	return sorted
+}
+
+// thinNotes caps a sorted chord at limit notes by dropping inner voices, keeping
+// the lowest (root/bass) and highest (colour/melody) tones. limit <= 0 means no cap.
+func thinNotes(notes []byte, limit int) []byte {
+	if limit <= 0 || len(notes) <= limit {
+		return notes
+	}
+	low := (limit + 1) / 2
+	high := limit - low
+	thinned := make([]byte, 0, limit)
+	thinned = append(thinned, notes[:low]...)
+	thinned = append(thinned, notes[len(notes)-high:]...)
+	return thinned
+}
+
+// foldIntoRange octave-shifts each note into [low, high] and returns the result
+// sorted and deduplicated. The range must span at least an octave so every
+// pitch class has a place in it.
+func foldIntoRange(notes []byte, low, high int) []byte {
+	var pitches []int
+	for _, n := range notes {
+		p := int(n)
+		for p < low {
+			p += 12
+		}
+		for p > high {
+			p -= 12
+		}
+		pitches = append(pitches, p)
+	}
+	sort.Ints(pitches)
+	var result []byte
+	for i, p := range pitches {
+		if i == 0 || p != pitches[i-1] {
+			result = append(result, byte(p))
+		}
+	}
+	return result
 }
 
 // noteAt returns notes[i%len(notes)]. Caller must ensure notes is non-empty.
@@ -101,6 +550,28 @@ func noteAt(notes []byte, i int) byte {
 	return notes[((i % n) + n) % n]
 }
 
+// noteClamped returns notes[i], counting from the top for negative i (-1 = highest),
+// and clamping out-of-range indices to the nearest end. notes must be non-empty.
+func noteClamped(notes []byte, i int) byte {
+	if i < 0 {
+		i += len(notes)
+	}
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(notes) {
+		i = len(notes) - 1
+	}
+	return notes[i]
+}
+
+// bossaClave holds the "bossa-2bar" comping eighths for the even and odd bar
+// of its two-bar cycle; the bass plays beats 1 and 3 of both.
+var bossaClave = [2][8]bool{
+	{true, false, false, true, false, false, true, false},
+	{false, false, true, false, false, true, false, false},
+}
+
 // lowerOctave returns note-12 (one octave down for bass lines), clamped to ≥ 0.
 func lowerOctave(note byte) byte {
 	if note < 12 {
@@ -109,25 +580,143 @@ func lowerOctave(note byte) byte {
 	return note - 12
 }
 
+// walkingLine returns one bass note per beat for a walking-bass bar over
+// chord: chord tones (root, third, fifth, seventh from chordIntervals, so a
+// request's customIntervals apply) an octave below notes[0], then a last beat
+// a half step above or below the next chord's root, whichever lies nearer the line.
+func walkingLine(chord string, custom map[string][]int, notes, next []byte, beats int) []byte {
+	root := int(lowerOctave(notes[0]))
+	tones := []int{0}
+	for _, iv := range chordIntervals(chord, custom) {
+		if iv > 0 && iv < 12 { // leave 9ths to the chord
+			tones = append(tones, iv)
+		}
+	}
+	slices.Sort(tones)
+	tones = slices.Compact(tones)
+	line := make([]byte, beats)
+	for b := 0; b < beats; b++ {
+		line[b] = byte(min(root+tones[b%len(tones)], 127))
+	}
+	if beats < 2 {
+		return line
+	}
+	target := int(lowerOctave(next[0]))
+	prev := int(line[beats-2])
+	approach := target - 1
+	if d := prev - (target + 1); d*d < (prev-approach)*(prev-approach) {
+		approach = target + 1
+	}
+	line[beats-1] = byte(max(0, min(approach, 127)))
+	return line
+}
+
+// scaleRun returns count notes climbing the chord's scale stepwise from its
+// root at or below notes[0]: natural minor when the quality (from
+// chordIntervals, so customIntervals apply) has a minor third, major
+// otherwise. Notes past 127 stay on the top note.
+func scaleRun(chord string, custom map[string][]int, notes []byte, count int) []byte {
+	root := int(notes[0])
+	if idx := chordRootIndex(chord); idx != -1 {
+		root -= (root%12 - idx + 12) % 12
+	}
+	intervals := chordIntervals(chord, custom)
+	scale := majorScale
+	if slices.Contains(intervals, 3) && !slices.Contains(intervals, 4) {
+		scale = minorScale
+	}
+	run := make([]byte, count)
+	for i := range run {
+		p := root + 12*(i/len(scale)) + scale[i%len(scale)]
+		run[i] = byte(max(0, min(p, 127)))
+	}
+	return run
+}
+
 // validPatterns is the set of all supported strumming/picking pattern names.
 var validPatterns = map[string]bool{
 	"whole": true, "half": true, "quarter": true,
 	"arpeggio-up": true, "arpeggio-down": true,
 	"boom-chick": true, "pop-strum": true, "travis-picking": true,
 	"alberti-bass": true, "triplet-arpeggio": true, "pop-stabs": true,
-	"bossa-nova": true, "reggae-skank": true, "funk-16th": true,
+	"bossa-nova": true, "bossa-2bar": true, "reggae-skank": true, "funk-16th": true,
 	"jazz-swing": true, "rock-8th": true,
 	"let-it-be": true, "stand-by-me": true, "creep-arpeggio": true,
 	"twist-and-shout": true, "blues-shuffle": true, "sweet-home-alabama": true,
 	"stairway-arpeggio": true, "hotel-california": true, "wonderwall-strum": true,
 	"blackbird-pick": true, "palm-mute-8th": true, "off-beat-8th": true,
 	"country-alt-bass": true, "pima-arpeggio": true, "four-on-the-floor": true,
+	"walking-bass": true, "scale-run": true, "arpeggio": true, "custom": true, "strum-dsl": true,
+}
+
+// maxSwingDelay caps SwingDelay at a sixteenth so it stays shorter than the
+// blues-shuffle upbeat it is taken from.
+const maxSwingDelay = ticksPerQuarter / 4
+
+// noteValueTicks maps rhythm note values to their length in ticks.
+// A trailing "." dots the value (1.5× its length).
+var noteValueTicks = map[string]uint32{
+	"w": ticksPerQuarter * 4,
+	"h": ticksPerQuarter * 2,
+	"q": ticksPerQuarter,
+	"e": ticksPerQuarter / 2,
+	"s": ticksPerQuarter / 4,
+}
+
+// rhythmTicks converts a list of note values into tick durations.
+func rhythmTicks(rhythm []string) ([]uint32, error) {
+	durs := make([]uint32, len(rhythm))
+	for i, v := range rhythm {
+		dotted := len(v) > 1 && v[len(v)-1] == '.'
+		if dotted {
+			v = v[:len(v)-1]
+		}
+		t, ok := noteValueTicks[v]
+		if !ok {
+			return nil, fmt.Errorf("unknown note value %q", rhythm[i])
+		}
+		if dotted {
+			t += t / 2
+		}
+		durs[i] = t
+	}
+	return durs, nil
+}
+
+// strumHit is one eighth-note step of a parsed strum DSL string.
+type strumHit struct {
+	active bool // false = rest
+	up     bool // up-strums sound the chord top to bottom
+}
+
+// parseStrumDSL parses a strum string such as "D-DU-UDU", one eighth note per
+// character: "D" down, "U" up, "-" rest.
+func parseStrumDSL(dsl string) ([]strumHit, error) {
+	if dsl == "" {
+		return nil, fmt.Errorf("must not be empty")
+	}
+	hits := make([]strumHit, len(dsl))
+	for i, ch := range dsl {
+		switch ch {
+		case 'D', 'd':
+			hits[i] = strumHit{active: true}
+		case 'U', 'u':
+			hits[i] = strumHit{active: true, up: true}
+		case '-':
+		default:
+			return nil, fmt.Errorf("invalid character %q at position %d (want D, U or -)", ch, i)
+		}
+	}
+	return hits, nil
 }
 
 // ── SMF (Standard MIDI File) writer ─────────────────────────────────────────
 
 const ticksPerQuarter = 480 // resolution
 
+// maxVarLen is the largest value a MIDI variable-length quantity can hold.
+const maxVarLen = 0x0FFFFFFF
+
 // varLen encodes a MIDI variable-length quantity.
 func varLen(v uint32) []byte {
 	if v < 0x80 {
@@ -155,9 +744,9 @@ func noteOnEvent(delta uint32, ch, note, vel byte) []byte {
 	return out
 }
 
-func noteOffEvent(delta uint32, ch, note byte) []byte {
+func noteOffEvent(delta uint32, ch, note, vel byte) []byte {
 	out := varLen(delta)
-	out = append(out, 0x80|ch, note, 0)
+	out = append(out, 0x80|ch, note, vel)
 	return out
 }
 
@@ -170,10 +759,510 @@ func tempoEvent(bpm int) []byte {
 	}
 }
 
+// markerEvent returns a marker meta event (FF 06) carrying text.
+func markerEvent(delta uint32, text string) []byte {
+	ev := append(varLen(delta), 0xFF, 0x06)
+	ev = append(ev, varLen(uint32(len(text)))...)
+	return append(ev, text...)
+}
+
+// graceTicks is how long a grace note sounds before the chord it leads into.
+const graceTicks = ticksPerQuarter / 16
+
+// graceNote returns a soft note a semitone below target, lasting graceTicks.
+func graceNote(target byte, offVel byte) []byte {
+	if target == 0 {
+		target = 1
+	}
+	ev := noteOnEvent(0, 0, target-1, 60)
+	return append(ev, noteOffEvent(graceTicks, 0, target-1, offVel)...)
+}
+
+// rubatoPeriodBars is how many chord slots one rubato swell lasts.
+const rubatoPeriodBars = 4
+
+// maxRubato bounds Rubato so the tempo never strays more than a quarter.
+const maxRubato = 0.25
+
+// rubatoTempo is the tempo for a chord slot under rubato: base pushed ahead and
+// held back by up to depth along a sine wave rubatoPeriodBars slots long.
+func rubatoTempo(base int, depth float64, slot int) int {
+	phase := 2 * math.Pi * float64(slot) / rubatoPeriodBars
+	return int(math.Round(float64(base) * (1 + depth*math.Sin(phase))))
+}
+
+// introStrumSpread is the gap in ticks between successive strings of the intro roll.
+const introStrumSpread = ticksPerQuarter / 8
+
+// introStrum renders a slow rolled strum of every open string lasting totalTicks.
+func introStrum(openMidi []int, totalTicks uint32, releaseVel byte) []byte {
+	var openStrings []byte
+	for _, m := range openMidi {
+		if m >= 0 && m <= 127 {
+			openStrings = append(openStrings, byte(m))
+		}
+	}
+	if len(openStrings) == 0 {
+		return nil
+	}
+	var trk []byte
+	var rolled uint32
+	for j, n := range openStrings {
+		var d uint32
+		if j > 0 {
+			d = introStrumSpread
+			rolled += d
+		}
+		trk = append(trk, noteOnEvent(d, 0, n, 90)...)
+	}
+	hold := uint32(0)
+	if totalTicks > rolled {
+		hold = totalTicks - rolled
+	}
+	for j, n := range openStrings {
+		var d uint32
+		if j == 0 {
+			d = hold
+		}
+		trk = append(trk, noteOffEvent(d, 0, n, releaseVel)...)
+	}
+	return trk
+}
+
+// maxStrumSpread caps DownSpread and UpSpread so a six-string strum still
+// fits comfortably inside a sixteenth note.
+const maxStrumSpread = ticksPerQuarter / 16
+
+// strumSpread returns the per-string gap for a down- or up-strum.
+func strumSpread(req MidiRequest, up bool) uint32 {
+	if up && req.UpSpread > 0 {
+		return req.UpSpread
+	}
+	return req.DownSpread
+}
+
+// strumChord sounds ordered one string after another, spread ticks apart, and
+// releases them together so the whole stroke lasts length ticks.
+func strumChord(ordered []byte, vel byte, spread, length uint32, offVel byte) []byte {
+	var trk []byte
+	var rolled uint32
+	for j, n := range ordered {
+		var d uint32
+		if j > 0 && rolled+spread < length {
+			d = spread
+			rolled += d
+		}
+		trk = append(trk, noteOnEvent(d, 0, n, vel)...)
+	}
+	for j, n := range ordered {
+		var d uint32
+		if j == 0 {
+			d = length - rolled
+		}
+		trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
+	}
+	return trk
+}
+
+// readVarLen decodes a variable-length quantity at b[i], returning the value
+// and the index just past it.
+func readVarLen(b []byte, i int) (uint32, int) {
+	var v uint32
+	for i < len(b) {
+		c := b[i]
+		i++
+		v = v<<7 | uint32(c&0x7F)
+		if c&0x80 == 0 {
+			break
+		}
+	}
+	return v, i
+}
+
+// truncateSlot cuts a rendered chord slot (note-on/off events only) to exactly
+// limit ticks: events after limit are dropped and any notes still sounding are
+// released at limit. A slot shorter than limit is padded with a rest.
+func truncateSlot(slot []byte, limit uint32, offVel byte) []byte {
+	var out []byte
+	var now, emitted uint32
+	sounding := map[byte]int{}
+	for i := 0; i < len(slot); {
+		delta, next := readVarLen(slot, i)
+		if next+3 > len(slot) {
+			break
+		}
+		status, note, vel := slot[next], slot[next+1], slot[next+2]
+		on := status&0xF0 == 0x90 && vel > 0
+		if now+delta > limit || (now+delta == limit && on) {
+			break
+		}
+		now += delta
+		out = append(out, varLen(now-emitted)...)
+		out = append(out, status, note, vel)
+		emitted = now
+		if on {
+			sounding[note]++
+		} else if sounding[note] > 0 {
+			sounding[note]--
+		}
+		i = next + 3
+	}
+
+	var held []int
+	for n, count := range sounding {
+		if count > 0 {
+			held = append(held, int(n))
+		}
+	}
+	sort.Ints(held)
+	if len(held) == 0 {
+		if emitted < limit {
+			out = append(out, noteOnEvent(0, 0, 0, 0)...)
+			out = append(out, noteOffEvent(limit-emitted, 0, 0, offVel)...)
+		}
+		return out
+	}
+	for j, n := range held {
+		var d uint32
+		if j == 0 {
+			d = limit - emitted
+		}
+		out = append(out, noteOffEvent(d, 0, byte(n), offVel)...)
+	}
+	return out
+}
+
+// pitchBendRange is the receiver's default bend range either way, in cents.
+const pitchBendRange = 200
+
+// pitchBendEvent encodes a pitch-bend to cents off the note's nominal pitch.
+func pitchBendEvent(delta uint32, ch byte, cents int) []byte {
+	v := 8192 + cents*8192/pitchBendRange
+	if v < 0 {
+		v = 0
+	}
+	if v > 16383 {
+		v = 16383
+	}
+	out := varLen(delta)
+	out = append(out, 0xE0|ch, byte(v&0x7F), byte(v>>7))
+	return out
+}
+
+// pitchStrings maps each fretted pitch to the index of the string that plays
+// it. The first (lowest) string wins when two strings share a pitch.
+func pitchStrings(frets []string, openMidi []int) map[byte]int {
+	strs := map[byte]int{}
+	for i, fv := range frets {
+		if i >= len(openMidi) {
+			break
+		}
+		fret, err := strconv.Atoi(fv)
+		if err != nil {
+			continue // muted
+		}
+		p := openMidi[i] + fret
+		if p < 0 || p > 127 {
+			continue
+		}
+		if _, ok := strs[byte(p)]; !ok {
+			strs[byte(p)] = i
+		}
+	}
+	return strs
+}
+
+// maxStringChannels is how many strings PerStringChannel can give their own
+// channel: all sixteen except the GM drum channel.
+const maxStringChannels = 15
+
+// stringChannel returns the MIDI channel for string index i, stepping over
+// the drum channel.
+func stringChannel(i int) byte {
+	if i >= drumChannel {
+		i++
+	}
+	return byte(i)
+}
+
+// assignChannels returns slot with each note event moved to the channel in
+// channels, keyed by pitch. Notes without an entry stay on their channel.
+func assignChannels(slot []byte, channels map[byte]byte) []byte {
+	out := make([]byte, 0, len(slot))
+	for i := 0; i < len(slot); {
+		_, next := readVarLen(slot, i)
+		if next+3 > len(slot) {
+			break
+		}
+		out = append(out, slot[i:next]...)
+		status, note, vel := slot[next], slot[next+1], slot[next+2]
+		if ch, ok := channels[note]; ok {
+			status = status&0xF0 | ch
+		}
+		out = append(out, status, note, vel)
+		i = next + 3
+	}
+	return out
+}
+
+// insertPitchBends returns slot with a pitch-bend ahead of every note-on whose
+// pitch has a cents offset. The bend takes the note-on's delta so timing is kept.
+func insertPitchBends(slot []byte, bends map[byte]int) []byte {
+	var out []byte
+	for i := 0; i < len(slot); {
+		delta, next := readVarLen(slot, i)
+		if next+3 > len(slot) {
+			break
+		}
+		status, note, vel := slot[next], slot[next+1], slot[next+2]
+		if cents, ok := bends[note]; ok && status&0xF0 == 0x90 && vel > 0 {
+			out = append(out, pitchBendEvent(delta, status&0x0F, cents)...)
+			delta = 0
+		}
+		out = append(out, varLen(delta)...)
+		out = append(out, status, note, vel)
+		i = next + 3
+	}
+	return out
+}
+
+// balanceNotes makes every note-on in a track pair with exactly one note-off.
+// Patterns that let notes ring can strike a pitch that is still held, and a
+// second note-on without a release hangs the note in some players: such a
+// note is released immediately before it is struck again. Note-offs left with
+// nothing to release are dropped (their delta carries forward), and anything
+// still held at the end is released. Rest placeholders (note 0) pass through.
+func balanceNotes(trk []byte, offVel byte) []byte {
+	var out []byte
+	var carry uint32
+	held := map[[2]byte]bool{} // channel, note
+	for i := 0; i < len(trk); {
+		delta, next := readVarLen(trk, i)
+		delta += carry
+		carry = 0
+		status := trk[next]
+		if status == 0xFF {
+			length, end := readVarLen(trk, next+2)
+			out = append(out, varLen(delta)...)
+			out = append(out, trk[next:end+int(length)]...)
+			i = end + int(length)
+			continue
+		}
+		note, vel := trk[next+1], trk[next+2]
+		i = next + 3
+		kind, key := status&0xF0, [2]byte{status & 0x0F, note}
+		if note == 0 || (kind != 0x80 && kind != 0x90) {
+			out = append(out, varLen(delta)...)
+			out = append(out, status, note, vel)
+			continue
+		}
+		if kind == 0x90 && vel > 0 {
+			if held[key] {
+				out = append(out, noteOffEvent(delta, key[0], note, offVel)...)
+				delta = 0
+			}
+			held[key] = true
+		} else if held[key] {
+			held[key] = false
+		} else {
+			carry = delta
+			continue
+		}
+		out = append(out, varLen(delta)...)
+		out = append(out, status, note, vel)
+	}
+
+	var keys [][2]byte
+	for key, on := range held {
+		if on {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b [2]byte) int {
+		if a[0] != b[0] {
+			return int(a[0]) - int(b[0])
+		}
+		return int(a[1]) - int(b[1])
+	})
+	for _, key := range keys {
+		out = append(out, noteOffEvent(carry, key[0], key[1], offVel)...)
+		carry = 0
+	}
+	if carry > 0 {
+		out = append(out, noteOnEvent(0, 0, 0, 0)...)
+		out = append(out, noteOffEvent(carry, 0, 0, offVel)...)
+	}
+	return out
+}
+
 func endOfTrack() []byte {
 	return []byte{0x00, 0xFF, 0x2F, 0x00}
 }
 
+// maxChordBeats bounds the inline duration of a "chord:beats" entry.
+const maxChordBeats = 16
+
+// splitChordBeats splits a lead-sheet entry like "C:2" into the chord name and
+// its length in beats. Entries without a colon return 0 beats (use the default).
+func splitChordBeats(entry string) (string, int, error) {
+	name, beats, ok := strings.Cut(entry, ":")
+	if !ok {
+		return entry, 0, nil
+	}
+	n, err := strconv.Atoi(beats)
+	if err != nil || n < 1 || n > maxChordBeats {
+		return "", 0, fmt.Errorf("%q: beats must be a whole number in range 1–%d", entry, maxChordBeats)
+	}
+	return name, n, nil
+}
+
+// beatsFor returns the length in beats of the chord at index ci.
+func beatsFor(req MidiRequest, ci int) int {
+	if ci < len(req.chordBeats) && req.chordBeats[ci] > 0 {
+		return req.chordBeats[ci]
+	}
+	return req.Beats
+}
+
+// passes returns how many times the chord list is played.
+func passes(req MidiRequest) int {
+	if req.Repeat < 1 {
+		return 1
+	}
+	return req.Repeat
+}
+
+// totalBars is the number of chord slots rendered across all passes.
+func totalBars(req MidiRequest) int {
+	return len(req.Chords) * passes(req)
+}
+
+// shiftNotes transposes notes by semitones, dropping any note that would pass
+// 127 by an octave (and raising any below 0) so it stays in MIDI range.
+func shiftNotes(notes []byte, semitones int) []byte {
+	if semitones == 0 {
+		return notes
+	}
+	shifted := make([]byte, len(notes))
+	for i, n := range notes {
+		p := int(n) + semitones
+		for p > 127 {
+			p -= 12
+		}
+		for p < 0 {
+			p += 12
+		}
+		shifted[i] = byte(p)
+	}
+	return shifted
+}
+
+// patternFor returns the pattern for the chord at index ci: the PatternSequence
+// entry (cycling) when one is given, otherwise the request-wide Pattern.
+func patternFor(req MidiRequest, ci int) string {
+	if len(req.PatternSequence) > 0 {
+		return req.PatternSequence[ci%len(req.PatternSequence)]
+	}
+	return req.Pattern
+}
+
+// slotNotes resolves the notes for one chord slot, along with any per-pitch
+// pitch bends (OpenCents) and channels (PerStringChannel). Real fret positions
+// win, then piano keys, then chord-quality intervals.
+func slotNotes(req MidiRequest, openMidi []int, slot int) ([]byte, map[byte]int, map[byte]byte) {
+	ci, pass := slot%len(req.Chords), slot/len(req.Chords)
+	shift := pass*req.RepeatTranspose + req.rootShift
+	chordName := req.Chords[ci]
+	var notes []byte
+	var bends map[byte]int
+	var channels map[byte]byte
+	if ci < len(req.Frets) && len(openMidi) > 0 {
+		notes = fretsToMidi(req.Frets[ci], openMidi)
+		if len(notes) > 0 && (len(req.OpenCents) > 0 || req.PerStringChannel) {
+			bends, channels = map[byte]int{}, map[byte]byte{}
+			for p, str := range pitchStrings(req.Frets[ci], openMidi) {
+				p = byte(int(p) + shift)
+				if len(req.OpenCents) > 0 {
+					bends[p] = req.OpenCents[str]
+				}
+				if req.PerStringChannel {
+					channels[p] = stringChannel(str)
+				}
+			}
+		}
+	}
+	if len(notes) == 0 && ci < len(req.Keys) {
+		notes = keysToMidi(req.Keys[ci])
+	}
+	if len(notes) == 0 {
+		notes = voiceChord(chordName, req.Octave, req.CustomIntervals)
+		switch req.Voicing {
+		case "drop2":
+			notes = dropVoicing(notes, 2)
+		case "drop3":
+			notes = dropVoicing(notes, 3)
+		}
+		if req.RangeHigh > 0 {
+			notes = foldIntoRange(notes, req.RangeLow, req.RangeHigh)
+		}
+		if req.CleanBass {
+			notes = cleanBass(notes)
+		}
+	}
+	if len(notes) == 0 {
+		return nil, nil, nil
+	}
+	notes = thinNotes(notes, req.MaxNotes)
+	notes = shiftNotes(notes, shift)
+	if req.bassOnly {
+		return []byte{lowerOctave(slices.Min(notes))}, nil, nil
+	}
+	return notes, bends, channels
+}
+
+// maxRootShift returns the (non-positive) semitone shift that brings the
+// first chord's root, its lowest note, down to req.MaxRootMidi.
+func maxRootShift(req MidiRequest) int {
+	if req.MaxRootMidi == 0 || len(req.Chords) == 0 {
+		return 0
+	}
+	notes, _, _ := slotNotes(req, tuning(req), 0)
+	if len(notes) == 0 {
+		return 0
+	}
+	if root := slices.Min(notes); root > req.MaxRootMidi {
+		return int(req.MaxRootMidi) - int(root)
+	}
+	return 0
+}
+
+// slotPattern returns the pattern rendered for a chord slot: the chord's own
+// pattern, or "whole" for the closing chord when FinalHold is set.
+func slotPattern(req MidiRequest, slot int) string {
+	if req.FinalHold && slot == totalBars(req)-1 {
+		return "whole" // ring out the closing chord for the full slot
+	}
+	return patternFor(req, slot%len(req.Chords))
+}
+
+// tiesAcross reports whether TieRepeats holds slot's chord over into slot+1:
+// both slots play the same chord with the same notes, and both use the
+// sustained "whole" pattern (rhythmic patterns always re-strike).
+func tiesAcross(req MidiRequest, openMidi []int, slot int) bool {
+	if !req.TieRepeats || slot+1 >= totalBars(req) {
+		return false
+	}
+	if req.Chords[slot%len(req.Chords)] != req.Chords[(slot+1)%len(req.Chords)] {
+		return false
+	}
+	if slotPattern(req, slot) != "whole" || slotPattern(req, slot+1) != "whole" {
+		return false
+	}
+	a, _, _ := slotNotes(req, openMidi, slot)
+	b, _, _ := slotNotes(req, openMidi, slot+1)
+	return bytes.Equal(a, b)
+}
+
 // buildTrack constructs the MTrk data bytes (without the "MTrk"+length header).
 func buildTrack(req MidiRequest) []byte {
 	var trk []byte
@@ -182,21 +1271,51 @@ func buildTrack(req MidiRequest) []byte {
 	trk = append(trk, tempoEvent(req.Tempo)...)
 
 	beatTicks := uint32(ticksPerQuarter) // ticks per beat
-	chordTicks := beatTicks * uint32(req.Beats)
+	offVel := req.ReleaseVelocity        // note-off (release) velocity
+	openMidi := tuning(req)
 
-	for ci, chordName := range req.Chords {
-		// Use real fret positions when available, fall back to chord-quality intervals.
-		var notes []byte
-		if ci < len(req.Frets) && len(req.OpenMidi) > 0 {
-			notes = fretsToMidi(req.Frets[ci], req.OpenMidi)
-		}
-		if len(notes) == 0 {
-			notes = chordToMidi(chordName, req.Octave)
-		}
+	if req.StartTick > 0 {
+		trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
+		trk = append(trk, noteOffEvent(req.StartTick, 0, 0, offVel)...)
+	}
+
+	if req.IntroStrum && len(openMidi) > 0 {
+		trk = append(trk, introStrum(openMidi, beatTicks*uint32(req.Beats), offVel)...)
+	}
+
+	// PushEighths lands every chord change early: the first chord gives up the
+	// pushed time and a closing rest restores the overall length.
+	push := uint32(req.PushEighths) * (beatTicks / 2)
+	pushed := false
+	baseTempo := req.Tempo // latest tempoMap BPM, which Rubato swells around
+	songBeats := 0         // beats before the current slot, for bar parity
+
+	// An empty chords list is allowed: with no slots the file holds just the
+	// tempo and end-of-track events.
+	for slot := 0; slot < totalBars(req); slot++ {
+		ci := slot % len(req.Chords)
+		beats := beatsFor(req, ci)
+		chordTicks := beatTicks * uint32(beats)
+		slotBeat := songBeats
+		songBeats += beats
+		notes, bends, channels := slotNotes(req, openMidi, slot)
 		if len(notes) == 0 {
 			continue // unrecognised chord — skip rather than panic
 		}
-		switch req.Pattern {
+		tieIn := slot > 0 && tiesAcross(req, openMidi, slot-1)
+		tieOut := tiesAcross(req, openMidi, slot)
+		for _, tc := range req.TempoMap {
+			if tc.ChordIndex == ci {
+				trk = append(trk, tempoEvent(tc.BPM)...)
+				baseTempo = tc.BPM
+			}
+		}
+		if req.Rubato > 0 && slot > 0 {
+			trk = append(trk, tempoEvent(rubatoTempo(baseTempo, req.Rubato, slot))...)
+		}
+		slotStart := len(trk)
+		pattern := slotPattern(req, slot)
+		switch pattern {
 
 		case "half":
 			// Two block chords per chord slot (each = beats/2)
@@ -217,13 +1336,13 @@ func buildTrack(req MidiRequest) []byte {
 					if j == 0 {
 						d = halfTicks
 					}
-					trk = append(trk, noteOffEvent(d, 0, n)...)
+					trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 				}
 			}
 
 		case "quarter":
 			// Block chord on every beat
-			for beat := 0; beat < req.Beats; beat++ {
+			for beat := 0; beat < beats; beat++ {
 				for j, n := range notes {
 					var d uint32
 					if j == 0 && beat > 0 {
@@ -236,7 +1355,7 @@ func buildTrack(req MidiRequest) []byte {
 					if j == 0 {
 						d = beatTicks
 					}
-					trk = append(trk, noteOffEvent(d, 0, n)...)
+					trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 				}
 			}
 
@@ -245,7 +1364,7 @@ func buildTrack(req MidiRequest) []byte {
 			noteDur := chordTicks / uint32(len(notes))
 			for _, n := range notes {
 				trk = append(trk, noteOnEvent(0, 0, n, 100)...)
-				trk = append(trk, noteOffEvent(noteDur, 0, n)...)
+				trk = append(trk, noteOffEvent(noteDur, 0, n, offVel)...)
 			}
 
 		case "arpeggio-down":
@@ -253,7 +1372,7 @@ func buildTrack(req MidiRequest) []byte {
 			noteDur := chordTicks / uint32(len(notes))
 			for i := len(notes) - 1; i >= 0; i-- {
 				trk = append(trk, noteOnEvent(0, 0, notes[i], 100)...)
-				trk = append(trk, noteOffEvent(noteDur, 0, notes[i])...)
+				trk = append(trk, noteOffEvent(noteDur, 0, notes[i], offVel)...)
 			}
 
 		case "boom-chick":
@@ -265,9 +1384,9 @@ func buildTrack(req MidiRequest) []byte {
 			}
 			// Beat 1: bass
 			trk = append(trk, noteOnEvent(0, 0, bassNote, 100)...)
-			trk = append(trk, noteOffEvent(beatTicks, 0, bassNote)...)
+			trk = append(trk, noteOffEvent(beatTicks, 0, bassNote, offVel)...)
 			// Remaining beats: chord stabs
-			for beat := 1; beat < req.Beats; beat++ {
+			for beat := 1; beat < beats; beat++ {
 				for j, n := range upperNotes {
 					var d uint32
 					if j > 0 {
@@ -280,7 +1399,7 @@ func buildTrack(req MidiRequest) []byte {
 					if j == 0 {
 						d = beatTicks
 					}
-					trk = append(trk, noteOffEvent(d, 0, n)...)
+					trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 				}
 			}
 
@@ -311,23 +1430,10 @@ func buildTrack(req MidiRequest) []byte {
 					} else {
 						copy(orderedNotes, notes)
 					}
-					for j, n := range orderedNotes {
-						var d uint32
-						if j > 0 {
-							d = 0
-						}
-						trk = append(trk, noteOnEvent(d, 0, n, sp.vel)...)
-					}
-					for j, n := range orderedNotes {
-						var d uint32
-						if j == 0 {
-							d = eighthTicks
-						}
-						trk = append(trk, noteOffEvent(d, 0, n)...)
-					}
+					trk = append(trk, strumChord(orderedNotes, sp.vel, strumSpread(req, sp.up), eighthTicks, offVel)...)
 				} else {
 					trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
-					trk = append(trk, noteOffEvent(eighthTicks, 0, 0)...)
+					trk = append(trk, noteOffEvent(eighthTicks, 0, 0, offVel)...)
 				}
 			}
 
@@ -353,7 +1459,7 @@ func buildTrack(req MidiRequest) []byte {
 					vel = 80
 				}
 				trk = append(trk, noteOnEvent(0, 0, n, vel)...)
-				trk = append(trk, noteOffEvent(eighthTicks, 0, n)...)
+				trk = append(trk, noteOffEvent(eighthTicks, 0, n, offVel)...)
 			}
 
 		case "alberti-bass":
@@ -380,8 +1486,73 @@ func buildTrack(req MidiRequest) []byte {
 						n = notes[0]
 					}
 				}
-				trk = append(trk, noteOnEvent(0, 0, n, 100)...)
-				trk = append(trk, noteOffEvent(eighthTicks, 0, n)...)
+				trk = append(trk, noteOnEvent(0, 0, n, 100)...)
+				trk = append(trk, noteOffEvent(eighthTicks, 0, n, offVel)...)
+			}
+
+		case "arpeggio":
+			// Arpeggio with Subdivision notes per beat (5 = quintuplets, 7 = septuplets),
+			// ascending or following ArpSequence. When the beat doesn't divide evenly
+			// the last note of each beat absorbs the remainder so the bar stays exact.
+			sub := uint32(req.Subdivision)
+			if sub == 0 {
+				sub = 2
+			}
+			stepTicks := beatTicks / sub
+			remainder := beatTicks - stepTicks*sub
+			for beat := 0; beat < beats; beat++ {
+				for si := uint32(0); si < sub; si++ {
+					step := beat*int(sub) + int(si)
+					n := noteAt(notes, step)
+					if len(req.ArpSequence) > 0 {
+						n = noteClamped(notes, req.ArpSequence[step%len(req.ArpSequence)])
+					}
+					d := stepTicks
+					if si == sub-1 {
+						d += remainder
+					}
+					trk = append(trk, noteOnEvent(0, 0, n, 100)...)
+					trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
+				}
+			}
+
+		case "custom":
+			// Block chord on each note value of the client-supplied rhythm
+			durs, _ := rhythmTicks(req.Rhythm)
+			for _, d := range durs {
+				for _, n := range notes {
+					trk = append(trk, noteOnEvent(0, 0, n, 100)...)
+				}
+				for j, n := range notes {
+					var off uint32
+					if j == 0 {
+						off = d
+					}
+					trk = append(trk, noteOffEvent(off, 0, n, offVel)...)
+				}
+			}
+
+		case "strum-dsl":
+			// Client-described strums, one eighth each, cycled across the slot
+			eighthTicks := beatTicks / 2
+			hits, _ := parseStrumDSL(req.StrumDSL)
+			totalEighths := int(chordTicks / eighthTicks)
+			for ei := 0; ei < totalEighths; ei++ {
+				if len(hits) == 0 || !hits[ei%len(hits)].active {
+					trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
+					trk = append(trk, noteOffEvent(eighthTicks, 0, 0, offVel)...)
+					continue
+				}
+				ordered := notes
+				vel := byte(100)
+				if hits[ei%len(hits)].up {
+					ordered = make([]byte, len(notes))
+					for k := range notes {
+						ordered[k] = notes[len(notes)-1-k]
+					}
+					vel = 80
+				}
+				trk = append(trk, strumChord(ordered, vel, strumSpread(req, hits[ei%len(hits)].up), eighthTicks, offVel)...)
 			}
 
 		case "triplet-arpeggio":
@@ -391,7 +1562,7 @@ func buildTrack(req MidiRequest) []byte {
 			for ti := 0; ti < totalTriplets; ti++ {
 				n := notes[ti%len(notes)]
 				trk = append(trk, noteOnEvent(0, 0, n, 100)...)
-				trk = append(trk, noteOffEvent(tripletTicks, 0, n)...)
+				trk = append(trk, noteOffEvent(tripletTicks, 0, n, offVel)...)
 			}
 
 		case "pop-stabs":
@@ -414,11 +1585,11 @@ func buildTrack(req MidiRequest) []byte {
 						if j == 0 {
 							d = eighthTicks
 						}
-						trk = append(trk, noteOffEvent(d, 0, n)...)
+						trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 					}
 				} else {
 					trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
-					trk = append(trk, noteOffEvent(eighthTicks, 0, 0)...)
+					trk = append(trk, noteOffEvent(eighthTicks, 0, 0, offVel)...)
 				}
 			}
 
@@ -434,7 +1605,7 @@ func buildTrack(req MidiRequest) []byte {
 				if ei%4 == 0 {
 					bassNote := lowerOctave(notes[0])
 					trk = append(trk, noteOnEvent(d, 0, bassNote, 100)...)
-					trk = append(trk, noteOffEvent(eighthTicks, 0, bassNote)...)
+					trk = append(trk, noteOffEvent(eighthTicks, 0, bassNote, offVel)...)
 					d = 0
 				}
 				// Chords
@@ -449,19 +1620,54 @@ func buildTrack(req MidiRequest) []byte {
 						} else {
 							d = 0
 						}
-						trk = append(trk, noteOffEvent(d, 0, n)...)
+						trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 						d = 0
 					}
 				} else if ei%4 != 0 {
 					// Silence for this eighth
 					trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
-					trk = append(trk, noteOffEvent(eighthTicks, 0, 0)...)
+					trk = append(trk, noteOffEvent(eighthTicks, 0, 0, offVel)...)
+				}
+			}
+
+		case "bossa-2bar":
+			// Two-bar clave: the comping answers itself across each pair of
+			// bars, counted from the start of the song so it runs on through
+			// chord changes.
+			eighthTicks := beatTicks / 2
+			barEighths := req.Beats * 2
+			for ei := 0; ei < beats*2; ei++ {
+				pos := slotBeat*2 + ei
+				parity, within := pos/barEighths%2, pos%barEighths
+				bass := within%4 == 0
+				comp := bossaClave[parity][within%8]
+				if !bass && !comp {
+					trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
+					trk = append(trk, noteOffEvent(eighthTicks, 0, 0, offVel)...)
+					continue
+				}
+				var sounding []byte
+				if bass {
+					sounding = append(sounding, lowerOctave(notes[0]))
+				}
+				if comp {
+					sounding = append(sounding, notes...)
+				}
+				for _, n := range sounding {
+					trk = append(trk, noteOnEvent(0, 0, n, 90)...)
+				}
+				for j, n := range sounding {
+					var d uint32
+					if j == 0 {
+						d = eighthTicks
+					}
+					trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 				}
 			}
 
 		case "reggae-skank":
 			// Staccato on 2 and 4
-			for beat := 0; beat < req.Beats; beat++ {
+			for beat := 0; beat < beats; beat++ {
 				if beat%2 == 1 { // Beats 2 and 4
 					for _, n := range notes {
 						trk = append(trk, noteOnEvent(0, 0, n, 110)...)
@@ -471,15 +1677,15 @@ func buildTrack(req MidiRequest) []byte {
 						if j == 0 {
 							d = beatTicks / 4 // Very staccato
 						}
-						trk = append(trk, noteOffEvent(d, 0, n)...)
+						trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 					}
 					// Wait for rest of beat
 					trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
-					trk = append(trk, noteOffEvent(3*beatTicks/4, 0, 0)...)
+					trk = append(trk, noteOffEvent(3*beatTicks/4, 0, 0, offVel)...)
 				} else {
 					// Silence for beats 1 and 3
 					trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
-					trk = append(trk, noteOffEvent(beatTicks, 0, 0)...)
+					trk = append(trk, noteOffEvent(beatTicks, 0, 0, offVel)...)
 				}
 			}
 
@@ -499,34 +1705,51 @@ func buildTrack(req MidiRequest) []byte {
 						if j == 0 {
 							d = sixteenthTicks
 						}
-						trk = append(trk, noteOffEvent(d, 0, n)...)
+						trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 					}
 				} else {
 					trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
-					trk = append(trk, noteOffEvent(sixteenthTicks, 0, 0)...)
+					trk = append(trk, noteOffEvent(sixteenthTicks, 0, 0, offVel)...)
 				}
 			}
 
 		case "jazz-swing":
-			// Charleston rhythm: 1, 2-and
+			// Charleston rhythm: 1, 2-and. SwingDelay lays the off-beat hit back;
+			// the following rest (or the hit itself) gives the time back.
 			eighthTicks := beatTicks / 2
 			totalEighths := int(chordTicks / eighthTicks)
 			pattern := []bool{true, false, false, true, false, false, false, false}
+			swing := uint32(req.SwingDelay)
+			var owed uint32 // ticks to take back from the next rest
 			for ei := 0; ei < totalEighths; ei++ {
 				if pattern[ei%8] {
-					for _, n := range notes {
-						trk = append(trk, noteOnEvent(0, 0, n, 100)...)
+					delay, length := uint32(0), eighthTicks
+					if ei%2 == 1 {
+						delay = swing
+						if ei+1 < totalEighths && !pattern[(ei+1)%8] {
+							owed = swing
+						} else {
+							length -= swing
+						}
 					}
 					for j, n := range notes {
 						d := uint32(0)
 						if j == 0 {
-							d = eighthTicks
+							d = delay
+						}
+						trk = append(trk, noteOnEvent(d, 0, n, 100)...)
+					}
+					for j, n := range notes {
+						d := uint32(0)
+						if j == 0 {
+							d = length
 						}
-						trk = append(trk, noteOffEvent(d, 0, n)...)
+						trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 					}
 				} else {
 					trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
-					trk = append(trk, noteOffEvent(eighthTicks, 0, 0)...)
+					trk = append(trk, noteOffEvent(eighthTicks-owed, 0, 0, offVel)...)
+					owed = 0
 				}
 			}
 
@@ -543,14 +1766,14 @@ func buildTrack(req MidiRequest) []byte {
 					if j == 0 {
 						d = eighthTicks
 					}
-					trk = append(trk, noteOffEvent(d, 0, n)...)
+					trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 				}
 			}
 
 		case "let-it-be":
 			// Piano ballad style: Quarters on 1, 2, 3, 4 with a subtle octaved root pulse
 			lowRoot := lowerOctave(notes[0])
-			for beat := 0; beat < req.Beats; beat++ {
+			for beat := 0; beat < beats; beat++ {
 				// Play chord
 				for _, n := range notes {
 					trk = append(trk, noteOnEvent(0, 0, n, 95)...)
@@ -558,9 +1781,9 @@ func buildTrack(req MidiRequest) []byte {
 				// Beat 1 and 3: add a lower octave root for depth
 				if beat%2 == 0 {
 					trk = append(trk, noteOnEvent(0, 0, lowRoot, 100)...)
-					trk = append(trk, noteOffEvent(beatTicks, 0, lowRoot)...)
+					trk = append(trk, noteOffEvent(beatTicks, 0, lowRoot, offVel)...)
 					for _, n := range notes {
-						trk = append(trk, noteOffEvent(0, 0, n)...)
+						trk = append(trk, noteOffEvent(0, 0, n, offVel)...)
 					}
 				} else {
 					for j, n := range notes {
@@ -568,7 +1791,7 @@ func buildTrack(req MidiRequest) []byte {
 						if j == 0 {
 							d = beatTicks
 						}
-						trk = append(trk, noteOffEvent(d, 0, n)...)
+						trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 					}
 				}
 			}
@@ -583,7 +1806,7 @@ func buildTrack(req MidiRequest) []byte {
 				p := pattern[ei%len(pattern)]
 				if p == 1 { // Bass
 					trk = append(trk, noteOnEvent(0, 0, bassNote, 110)...)
-					trk = append(trk, noteOffEvent(eighthTicks, 0, bassNote)...)
+					trk = append(trk, noteOffEvent(eighthTicks, 0, bassNote, offVel)...)
 				} else if p == 2 { // Stab
 					for _, n := range notes {
 						trk = append(trk, noteOnEvent(0, 0, n, 90)...)
@@ -593,11 +1816,11 @@ func buildTrack(req MidiRequest) []byte {
 						if j == 0 {
 							d = eighthTicks
 						}
-						trk = append(trk, noteOffEvent(d, 0, n)...)
+						trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 					}
 				} else { // Rest
 					trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
-					trk = append(trk, noteOffEvent(eighthTicks, 0, 0)...)
+					trk = append(trk, noteOffEvent(eighthTicks, 0, 0, offVel)...)
 				}
 			}
 
@@ -607,7 +1830,7 @@ func buildTrack(req MidiRequest) []byte {
 			for ei := 0; ei < 8; ei++ {
 				n := notes[ei%len(notes)]
 				trk = append(trk, noteOnEvent(0, 0, n, 100)...)
-				trk = append(trk, noteOffEvent(eighthTicks, 0, n)...)
+				trk = append(trk, noteOffEvent(eighthTicks, 0, n, offVel)...)
 			}
 
 		case "twist-and-shout":
@@ -630,19 +1853,10 @@ func buildTrack(req MidiRequest) []byte {
 							ordered[k] = notes[len(notes)-1-k]
 						}
 					}
-					for _, n := range ordered {
-						trk = append(trk, noteOnEvent(0, 0, n, 105)...)
-					}
-					for j, n := range ordered {
-						d := uint32(0)
-						if j == 0 {
-							d = eighthTicks
-						}
-						trk = append(trk, noteOffEvent(d, 0, n)...)
-					}
+					trk = append(trk, strumChord(ordered, 105, strumSpread(req, sp.up), eighthTicks, offVel)...)
 				} else {
 					trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
-					trk = append(trk, noteOffEvent(eighthTicks, 0, 0)...)
+					trk = append(trk, noteOffEvent(eighthTicks, 0, 0, offVel)...)
 				}
 			}
 
@@ -650,7 +1864,7 @@ func buildTrack(req MidiRequest) []byte {
 			// Swung eighth notes: long-short (triplet feel)
 			longTicks := (beatTicks * 2) / 3
 			shortTicks := beatTicks / 3
-			for beat := 0; beat < req.Beats; beat++ {
+			for beat := 0; beat < beats; beat++ {
 				// Downbeat (long)
 				for _, n := range notes {
 					trk = append(trk, noteOnEvent(0, 0, n, 110)...)
@@ -660,18 +1874,22 @@ func buildTrack(req MidiRequest) []byte {
 					if j == 0 {
 						d = longTicks
 					}
-					trk = append(trk, noteOffEvent(d, 0, n)...)
+					trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 				}
-				// Upbeat (short)
-				for _, n := range notes {
-					trk = append(trk, noteOnEvent(0, 0, n, 90)...)
+				// Upbeat (short), laid back by SwingDelay
+				for j, n := range notes {
+					d := uint32(0)
+					if j == 0 {
+						d = uint32(req.SwingDelay)
+					}
+					trk = append(trk, noteOnEvent(d, 0, n, 90)...)
 				}
 				for j, n := range notes {
 					d := uint32(0)
 					if j == 0 {
-						d = shortTicks
+						d = shortTicks - uint32(req.SwingDelay)
 					}
-					trk = append(trk, noteOffEvent(d, 0, n)...)
+					trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 				}
 			}
 
@@ -693,7 +1911,7 @@ func buildTrack(req MidiRequest) []byte {
 					vel = 90
 				}
 				trk = append(trk, noteOnEvent(0, 0, n, vel)...)
-				trk = append(trk, noteOffEvent(eighthTicks, 0, n)...)
+				trk = append(trk, noteOffEvent(eighthTicks, 0, n, offVel)...)
 			}
 
 		case "stairway-arpeggio":
@@ -717,7 +1935,7 @@ func buildTrack(req MidiRequest) []byte {
 				case 7: n = notes[0]
 				}
 				trk = append(trk, noteOnEvent(0, 0, n, 100)...)
-				trk = append(trk, noteOffEvent(eighthTicks, 0, n)...)
+				trk = append(trk, noteOffEvent(eighthTicks, 0, n, offVel)...)
 			}
 
 		case "hotel-california":
@@ -735,7 +1953,7 @@ func buildTrack(req MidiRequest) []byte {
 				if idx >= len(notes) { idx = len(notes)-1 }
 				n = notes[idx]
 				trk = append(trk, noteOnEvent(0, 0, n, 100)...)
-				trk = append(trk, noteOffEvent(eighthTicks, 0, n)...)
+				trk = append(trk, noteOffEvent(eighthTicks, 0, n, offVel)...)
 			}
 
 		case "wonderwall-strum":
@@ -750,11 +1968,11 @@ func buildTrack(req MidiRequest) []byte {
 					for j, n := range notes {
 						d := uint32(0)
 						if j == 0 { d = sixteenthTicks }
-						trk = append(trk, noteOffEvent(d, 0, n)...)
+						trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 					}
 				} else {
 					trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
-					trk = append(trk, noteOffEvent(sixteenthTicks, 0, 0)...)
+					trk = append(trk, noteOffEvent(sixteenthTicks, 0, 0, offVel)...)
 				}
 			}
 
@@ -766,12 +1984,12 @@ func buildTrack(req MidiRequest) []byte {
 					// Pluck Bass + High
 					trk = append(trk, noteOnEvent(0, 0, notes[0], 110)...)
 					trk = append(trk, noteOnEvent(0, 0, notes[len(notes)-1], 100)...)
-					trk = append(trk, noteOffEvent(eighthTicks, 0, notes[0])...)
-					trk = append(trk, noteOffEvent(0, 0, notes[len(notes)-1])...)
+					trk = append(trk, noteOffEvent(eighthTicks, 0, notes[0], offVel)...)
+					trk = append(trk, noteOffEvent(0, 0, notes[len(notes)-1], offVel)...)
 				} else {
 					// Filler
 					trk = append(trk, noteOnEvent(0, 0, notes[1%len(notes)], 80)...)
-					trk = append(trk, noteOffEvent(eighthTicks, 0, notes[1%len(notes)])...)
+					trk = append(trk, noteOffEvent(eighthTicks, 0, notes[1%len(notes)], offVel)...)
 				}
 			}
 
@@ -785,11 +2003,11 @@ func buildTrack(req MidiRequest) []byte {
 				for j, n := range notes {
 					d := uint32(0)
 					if j == 0 { d = eighthTicks / 2 }
-					trk = append(trk, noteOffEvent(d, 0, n)...)
+					trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 				}
 				// rest of eighth
 				trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
-				trk = append(trk, noteOffEvent(eighthTicks/2, 0, 0)...)
+				trk = append(trk, noteOffEvent(eighthTicks/2, 0, 0, offVel)...)
 			}
 
 		case "off-beat-8th":
@@ -803,11 +2021,11 @@ func buildTrack(req MidiRequest) []byte {
 					for j, n := range notes {
 						d := uint32(0)
 						if j == 0 { d = eighthTicks }
-						trk = append(trk, noteOffEvent(d, 0, n)...)
+						trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 					}
 				} else {
 					trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
-					trk = append(trk, noteOffEvent(eighthTicks, 0, 0)...)
+					trk = append(trk, noteOffEvent(eighthTicks, 0, 0, offVel)...)
 				}
 			}
 
@@ -824,11 +2042,11 @@ func buildTrack(req MidiRequest) []byte {
 				if beat == 0 {
 					// Root bass
 					trk = append(trk, noteOnEvent(0, 0, lowerOctave(bassRoot), 110)...)
-					trk = append(trk, noteOffEvent(beatTicks, 0, lowerOctave(bassRoot))...)
+					trk = append(trk, noteOffEvent(beatTicks, 0, lowerOctave(bassRoot), offVel)...)
 				} else if beat == 2 {
 					// Fifth bass
 					trk = append(trk, noteOnEvent(0, 0, lowerOctave(bassFifth), 110)...)
-					trk = append(trk, noteOffEvent(beatTicks, 0, lowerOctave(bassFifth))...)
+					trk = append(trk, noteOffEvent(beatTicks, 0, lowerOctave(bassFifth), offVel)...)
 				} else {
 					// Strum
 					for _, n := range notes {
@@ -837,7 +2055,7 @@ func buildTrack(req MidiRequest) []byte {
 					for j, n := range notes {
 						d := uint32(0)
 						if j == 0 { d = beatTicks }
-						trk = append(trk, noteOffEvent(d, 0, n)...)
+						trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 					}
 				}
 			}
@@ -851,7 +2069,7 @@ func buildTrack(req MidiRequest) []byte {
 				if idx >= len(notes) { idx = len(notes)-1 }
 				n := notes[idx]
 				trk = append(trk, noteOnEvent(0, 0, n, 100)...)
-				trk = append(trk, noteOffEvent(eighthTicks, 0, n)...)
+				trk = append(trk, noteOffEvent(eighthTicks, 0, n, offVel)...)
 			}
 
 		case "four-on-the-floor":
@@ -865,95 +2083,665 @@ func buildTrack(req MidiRequest) []byte {
 				for j, n := range notes {
 					d := uint32(0)
 					if j == 0 { d = beatTicks }
-					trk = append(trk, noteOffEvent(d, 0, n)...)
+					trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 				}
 			}
 
-		default: // "whole" — one block chord for the entire duration
-			for j, n := range notes {
-				var d uint32
-				if j > 0 {
-					d = 0
+		case "walking-bass":
+			// Quarter-note bass line: chord tones, then an approach into the next chord
+			next, _, _ := slotNotes(req, openMidi, (slot+1)%totalBars(req))
+			if len(next) == 0 {
+				next = notes
+			}
+			for beat, n := range walkingLine(req.Chords[ci], req.CustomIntervals, notes, next, beats) {
+				vel := byte(85)
+				if beat == 0 {
+					vel = 100
 				}
-				trk = append(trk, noteOnEvent(d, 0, n, 100)...)
+				trk = append(trk, noteOnEvent(0, 0, n, vel)...)
+				trk = append(trk, noteOffEvent(beatTicks, 0, n, offVel)...)
+			}
+
+		case "scale-run":
+			// Sixteenth-note run up the chord's scale, for technique practice.
+			// notes are already transposed, so the scale follows the sounding chord.
+			sounding := transposeChord(req.Chords[ci], slot/len(req.Chords)*req.RepeatTranspose+req.rootShift)
+			for i, n := range scaleRun(sounding, req.CustomIntervals, notes, beats*4) {
+				vel := byte(80)
+				if i%4 == 0 {
+					vel = 95
+				}
+				trk = append(trk, noteOnEvent(0, 0, n, vel)...)
+				trk = append(trk, noteOffEvent(beatTicks/4, 0, n, offVel)...)
+			}
+
+		default: // "whole" — one block chord for the entire duration
+			// A tied repeat keeps the previous slot's notes sounding instead of
+			// re-striking them, and a slot tied onward holds through a rest.
+			// AttackEachBar re-strikes a chord longer than a bar on every bar line.
+			bar := chordTicks
+			if req.AttackEachBar {
+				bar = min(chordTicks, beatTicks*uint32(req.Beats))
 			}
-			for j, n := range notes {
-				var d uint32
-				if j == 0 {
-					d = chordTicks
+			for start := uint32(0); start < chordTicks; start += bar {
+				hold := min(bar, chordTicks-start)
+				if !tieIn {
+					for _, n := range notes {
+						trk = append(trk, noteOnEvent(0, 0, n, 100)...)
+					}
+				}
+				if tieOut {
+					trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
+					trk = append(trk, noteOffEvent(hold, 0, 0, offVel)...)
+					continue
+				}
+				for j, n := range notes {
+					var d uint32
+					if j == 0 {
+						d = hold
+					}
+					trk = append(trk, noteOffEvent(d, 0, n, offVel)...)
 				}
-				trk = append(trk, noteOffEvent(d, 0, n)...)
 			}
 		}
+
+		if req.Swing > 0 && swingPatterns[pattern] {
+			slot := applySwing(trk[slotStart:], beatTicks, req.Swing)
+			trk = append(trk[:slotStart], slot...)
+		}
+		if (req.Feel == "double" || req.Feel == "half") && !tieIn && !tieOut {
+			slot := applyFeel(trk[slotStart:], req.Feel, chordTicks, offVel)
+			trk = append(trk[:slotStart], slot...)
+		}
+		slotTicks := chordTicks
+		if push > 0 && !pushed && totalBars(req) > 1 {
+			slotTicks -= push
+			slot := truncateSlot(trk[slotStart:], slotTicks, offVel)
+			trk = append(trk[:slotStart], slot...)
+			pushed = true
+		}
+		// The grace note borrows the end of this slot so bar lines don't move.
+		if req.GraceNotes && !tieOut && slot+1 < totalBars(req) && slotTicks > graceTicks {
+			if next, _, _ := slotNotes(req, openMidi, slot+1); len(next) > 0 {
+				slot := truncateSlot(trk[slotStart:], slotTicks-graceTicks, offVel)
+				trk = append(append(trk[:slotStart], slot...), graceNote(slices.Min(next), offVel)...)
+			}
+		}
+		if len(channels) > 0 {
+			slot := assignChannels(trk[slotStart:], channels)
+			trk = append(trk[:slotStart], slot...)
+		}
+		if len(bends) > 0 {
+			slot := insertPitchBends(trk[slotStart:], bends)
+			trk = append(trk[:slotStart], slot...)
+		}
+	}
+	if pushed {
+		trk = append(trk, noteOnEvent(0, 0, 0, 0)...)
+		trk = append(trk, noteOffEvent(push, 0, 0, offVel)...)
 	}
 
+	trk = balanceNotes(trk, offVel)
 	trk = append(trk, endOfTrack()...)
 	return trk
 }
 
-// buildMidi returns a complete SMF format-0 MIDI file.
+// buildTracks renders the MTrk data for a request: the chord track, followed
+// by the drum and click tracks when requested.
+func buildTracks(req MidiRequest) [][]byte {
+	tracks := [][]byte{buildTrack(req)}
+	if req.Drums {
+		tracks = append(tracks, buildDrumTrack(req))
+	}
+	if req.ClickSubdivision != "" {
+		tracks = append(tracks, buildClickTrack(req))
+	}
+	if req.MarkerTrack {
+		tracks = append(tracks, buildMarkerTrack(req))
+	}
+	return tracks
+}
+
+// buildMarkerTrack renders a track holding only marker meta events, one with
+// the sounding chord name at the start of every chord slot, ending with the song.
+func buildMarkerTrack(req MidiRequest) []byte {
+	var trk []byte
+	var last uint32
+	slotStart := leadTicks(req)
+	for slot := 0; slot < totalBars(req); slot++ {
+		ci := slot % len(req.Chords)
+		name := req.Chords[ci]
+		if shift := slot/len(req.Chords)*req.RepeatTranspose + req.rootShift; shift != 0 {
+			name = transposeChord(name, shift)
+		}
+		trk = append(trk, markerEvent(slotStart-last, name)...)
+		last = slotStart
+		slotStart += uint32(ticksPerQuarter * beatsFor(req, ci))
+	}
+	trk = append(trk, varLen(slotStart-last)...)
+	return append(trk, 0xFF, 0x2F, 0x00)
+}
+
+// buildStems renders the chord and bass stems of a request. The bass stem
+// plays "whole" whatever the pattern, and starts where the chords do.
+func buildStems(req MidiRequest) *MidiStems {
+	bass := req
+	bass.bassOnly = true
+	bass.Pattern, bass.PatternSequence = "whole", nil
+	bass.Feel, bass.GraceNotes = "", false
+	bass.StartTick, bass.IntroStrum = leadTicks(req), false
+	return &MidiStems{
+		Chord: writeSMF([][]byte{buildTrack(req)}),
+		Bass:  writeSMF([][]byte{buildTrack(bass)}),
+	}
+}
+
+// buildMidi returns a complete SMF file: format 0 for a single track, or
+// format 1 when drum or click tracks are added alongside the chords.
 func buildMidi(req MidiRequest) []byte {
-	trackData := buildTrack(req)
+	return writeSMF(buildTracks(req))
+}
+
+// writeSMF wraps MTrk data chunks in a Standard MIDI File.
+func writeSMF(tracks [][]byte) []byte {
+	format := uint16(0)
+	if len(tracks) > 1 {
+		format = 1
+	}
 
 	var buf bytes.Buffer
 	// ── MThd ──
 	buf.WriteString("MThd")
 	binary.Write(&buf, binary.BigEndian, uint32(6))  // header length
-	binary.Write(&buf, binary.BigEndian, uint16(0))  // format 0
-	binary.Write(&buf, binary.BigEndian, uint16(1))  // 1 track
+	binary.Write(&buf, binary.BigEndian, format)     // format 0 or 1
+	binary.Write(&buf, binary.BigEndian, uint16(len(tracks))) // track count
 	binary.Write(&buf, binary.BigEndian, uint16(ticksPerQuarter))
 
 	// ── MTrk ──
-	buf.WriteString("MTrk")
-	binary.Write(&buf, binary.BigEndian, uint32(len(trackData)))
-	buf.Write(trackData)
+	for _, trackData := range tracks {
+		buf.WriteString("MTrk")
+		binary.Write(&buf, binary.BigEndian, uint32(len(trackData)))
+		buf.Write(trackData)
+	}
 
 	return buf.Bytes()
 }
 
-// GenerateMidi handles POST /api/midi
-func GenerateMidi(c *gin.Context) {
+// bindMidiRequest binds and validates a MidiRequest and applies its defaults.
+// On failure it writes the 400 response itself and returns false.
+func bindMidiRequest(c *gin.Context) (MidiRequest, bool) {
 	var req MidiRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return req, false
+	}
+	req, err := validateMidiRequest(c, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
 	}
+	return req, true
+}
 
-	// Validate required fields
-	if len(req.Chords) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "chords must not be empty"})
-		return
+// validateMidiRequest checks a bound MidiRequest and applies its defaults,
+// returning the first problem found.
+func validateMidiRequest(c *gin.Context, req MidiRequest) (MidiRequest, error) {
+	for i, entry := range req.Chords {
+		name, beats, err := splitChordBeats(entry)
+		if err != nil {
+			return req, fmt.Errorf("chords[%d]: %v", i, err)
+		}
+		if beats > 0 {
+			if req.chordBeats == nil {
+				req.chordBeats = make([]int, len(req.Chords))
+			}
+			req.Chords[i], req.chordBeats[i] = name, beats
+		}
 	}
 	for _, m := range req.OpenMidi {
 		if m < 0 || m > 127 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "openMidi values must be in range 0–127"})
-			return
+			return req, errors.New("openMidi values must be in range 0–127")
+		}
+	}
+	// In fret mode every non-empty frets row must cover each string exactly;
+	// ?lenient=true keeps the old behaviour of ignoring strings past the tuning.
+	// Empty rows are chords without a diagram and fall back to chord-quality notes.
+	if len(req.OpenMidi) > 0 && c.Query("lenient") != "true" {
+		for ci, row := range req.Frets {
+			if len(row) > 0 && len(row) != len(req.OpenMidi) {
+				return req, fmt.Errorf("frets[%d] has %d strings but openMidi has %d", ci, len(row), len(req.OpenMidi))
+			}
+		}
+	}
+	if req.PerStringChannel && len(req.OpenMidi) > maxStringChannels {
+		return req, fmt.Errorf("perStringChannel supports at most %d strings", maxStringChannels)
+	}
+	// Pitch bend moves a whole channel, so each string's bend needs a channel
+	// of its own or the chord's last bend would retune every note.
+	if len(req.OpenCents) > 0 && !req.PerStringChannel {
+		return req, errors.New("openCents needs perStringChannel")
+	}
+	if len(req.OpenCents) > 0 && len(req.OpenCents) != len(req.OpenMidi) {
+		return req, fmt.Errorf("openCents has %d entries but openMidi has %d", len(req.OpenCents), len(req.OpenMidi))
+	}
+	for _, cents := range req.OpenCents {
+		if cents < -100 || cents > 100 {
+			return req, errors.New("openCents values must be in range -100–100")
+		}
+	}
+	if req.Capo < 0 || req.Capo > 12 {
+		return req, errors.New("capo must be in range 0–12")
+	}
+	for _, i := range req.CapoStrings {
+		if i < 0 || i >= len(req.OpenMidi) {
+			return req, fmt.Errorf("capoStrings: no string %d in openMidi", i)
+		}
+	}
+	for i, m := range req.OpenMidiOverrides {
+		if i < 0 || i >= len(req.OpenMidi) {
+			return req, fmt.Errorf("openMidiOverrides: no string %d in openMidi", i)
+		}
+		if m < 0 || m > 127 {
+			return req, errors.New("openMidiOverrides values must be in range 0–127")
+		}
+	}
+	for suffix, intervals := range req.CustomIntervals {
+		if len(intervals) == 0 {
+			return req, fmt.Errorf("customIntervals[%q]: at least one interval is required", suffix)
+		}
+		for _, iv := range intervals {
+			if iv < 0 || iv > 127 {
+				return req, fmt.Errorf("customIntervals[%q]: intervals must be in range 0–127", suffix)
+			}
 		}
 	}
 
 	// Apply defaults
 	if req.Tempo <= 0 || req.Tempo > 300 {
+		if req.Tempo != 0 {
+			req.clamped = append(req.clamped, fmt.Sprintf("tempo %d out of range, used 120", req.Tempo))
+		}
 		req.Tempo = 120
 	}
 	if req.Octave < 0 || req.Octave > 8 {
+		req.clamped = append(req.clamped, fmt.Sprintf("octave %d out of range, used 4", req.Octave))
 		req.Octave = 4
 	}
 	if req.Beats <= 0 {
+		if req.Beats != 0 {
+			req.clamped = append(req.clamped, fmt.Sprintf("beats %d out of range, used 4", req.Beats))
+		}
 		req.Beats = 4
 	}
+	if req.ReleaseVelocity > 127 {
+		return req, errors.New("releaseVelocity must be in range 0–127")
+	}
+	if req.SwingDelay < 0 || req.SwingDelay > maxSwingDelay {
+		return req, fmt.Errorf("swingDelay must be in range 0–%d", maxSwingDelay)
+	}
+	for ci, row := range req.Keys {
+		for _, k := range row {
+			if keyNameToMidi(k) == -1 {
+				return req, fmt.Errorf("keys[%d]: invalid key %q", ci, k)
+			}
+		}
+	}
+	if req.StartTick > maxVarLen {
+		return req, fmt.Errorf("startTick must be at most %d", maxVarLen)
+	}
+	if req.Swing != 0 && (req.Swing < 50 || req.Swing > 75) {
+		return req, errors.New("swing must be 0 or in range 50–75")
+	}
+	// Only the first chord gives up the pushed time, so it must be long enough.
+	if first := beatsFor(req, 0); req.PushEighths < 0 || req.PushEighths >= first*2 {
+		return req, fmt.Errorf("pushEighths must be in range 0–%d", first*2-1)
+	}
+	if req.RangeLow != 0 || req.RangeHigh != 0 {
+		if req.RangeLow < 0 || req.RangeHigh > 127 || req.RangeHigh-req.RangeLow < 11 {
+			return req, errors.New("rangeLow–rangeHigh must lie within 0–127 and span at least an octave")
+		}
+	}
+	if req.Instrument != "" {
+		inst, err := findInstrument(req.Instrument)
+		if err != nil {
+			return req, err
+		}
+		// Fret rows for another instrument would map onto the wrong strings.
+		for ci, row := range req.Frets {
+			if len(row) > 0 && inst.Strings > 0 && len(row) != inst.Strings {
+				return req, fmt.Errorf("frets[%d] has %d strings but %s has %d", ci, len(row), inst.Key, inst.Strings)
+			}
+		}
+		// An explicit rangeLow/rangeHigh wins over the instrument's range.
+		if req.RangeHigh == 0 && inst.MaxMidi > 0 {
+			req.RangeLow, req.RangeHigh = inst.MinMidi, inst.MaxMidi
+		}
+	}
+	for _, tc := range req.TempoMap {
+		if tc.ChordIndex < 0 || tc.ChordIndex >= len(req.Chords) {
+			return req, fmt.Errorf("tempoMap chordIndex %d out of range 0–%d", tc.ChordIndex, len(req.Chords)-1)
+		}
+		if tc.BPM <= 0 || tc.BPM > 300 {
+			return req, errors.New("tempoMap bpm must be in range 1–300")
+		}
+	}
+	if req.Repeat < 0 || req.Repeat > 16 {
+		return req, errors.New("repeat must be in range 1–16")
+	}
+	if req.RepeatTranspose < -12 || req.RepeatTranspose > 12 {
+		return req, errors.New("repeatTranspose must be in range -12–12")
+	}
+	if req.MaxNotes < 0 {
+		return req, errors.New("maxNotes must not be negative")
+	}
+	if req.Subdivision < 0 || req.Subdivision > 16 {
+		return req, errors.New("subdivision must be in range 1–16")
+	}
 	if req.Pattern == "" {
 		req.Pattern = "quarter"
 	}
+	if _, ok := gmDrums[req.Backbeat]; req.Backbeat != "" && !ok {
+		return req, errors.New("unknown drum: " + req.Backbeat)
+	}
+	if req.DownSpread > maxStrumSpread || req.UpSpread > maxStrumSpread {
+		return req, fmt.Errorf("downSpread and upSpread must be in range 0–%d", maxStrumSpread)
+	}
+	if req.MaxRootMidi > 127 {
+		return req, errors.New("maxRootMidi must be in range 0–127")
+	}
+	req.rootShift = maxRootShift(req)
+	if _, ok := clickTicks[req.ClickSubdivision]; req.ClickSubdivision != "" && !ok {
+		return req, errors.New("clickSubdivision must be \"quarter\" or \"eighth\"")
+	}
+	if req.Feel != "" && req.Feel != "normal" && req.Feel != "double" && req.Feel != "half" {
+		return req, errors.New("feel must be \"normal\", \"double\" or \"half\"")
+	}
+	if req.Voicing != "" && req.Voicing != "close" && req.Voicing != "drop2" && req.Voicing != "drop3" {
+		return req, errors.New("voicing must be \"close\", \"drop2\" or \"drop3\"")
+	}
+	if req.AttackEachBar && req.TieRepeats {
+		return req, errors.New("attackEachBar and tieRepeats cannot be combined")
+	}
+	if req.Rubato < 0 || req.Rubato > maxRubato {
+		return req, fmt.Errorf("rubato must be in range 0–%g", maxRubato)
+	}
+	if req.ClickDropAfter < 0 {
+		return req, errors.New("clickDropAfter must not be negative")
+	}
+	if req.ClickDropAfter > 0 && req.ClickSubdivision == "" {
+		return req, errors.New("clickDropAfter needs clickSubdivision")
+	}
+	if req.Format != "" && req.Format != "midi" && req.Format != "json" {
+		return req, errors.New("format must be \"midi\" or \"json\"")
+	}
+	if req.Stems && req.Format != "json" {
+		return req, errors.New("stems needs format \"json\"")
+	}
+
+	// Validate pattern names (the base pattern plus any per-chord sequence)
+	usesCustom, usesDSL := false, false
+	for _, p := range append([]string{req.Pattern}, req.PatternSequence...) {
+		if !validPatterns[p] {
+			requestLogger(c).Warn("midi rejected", "pattern", p, "reason", "unknown pattern")
+			return req, errors.New("unknown pattern: " + p)
+		}
+		if p == "custom" {
+			usesCustom = true
+		}
+		if p == "strum-dsl" {
+			usesDSL = true
+		}
+	}
+	// custom rhythms and strum strings are written for one bar length.
+	if usesCustom || usesDSL {
+		for ci := range req.Chords {
+			if beatsFor(req, ci) != req.Beats {
+				return req, errors.New("inline chord durations can't be combined with the custom or strum-dsl patterns")
+			}
+		}
+	}
+	if usesDSL {
+		hits, err := parseStrumDSL(req.StrumDSL)
+		if err != nil {
+			return req, fmt.Errorf("strumDSL: %w", err)
+		}
+		// The string must tile the bar: each eighth of it gets exactly one step.
+		if eighths := req.Beats * 2; len(hits) > eighths || eighths%len(hits) != 0 {
+			return req, fmt.Errorf("strumDSL has %d steps, which doesn't divide the %d eighths of a bar", len(hits), eighths)
+		}
+	}
+	if usesCustom {
+		durs, err := rhythmTicks(req.Rhythm)
+		if err != nil {
+			return req, fmt.Errorf("rhythm: %w", err)
+		}
+		var total uint32
+		for _, d := range durs {
+			total += d
+		}
+		if want := uint32(ticksPerQuarter * req.Beats); total != want {
+			return req, fmt.Errorf("rhythm lasts %g beats, want %d", float64(total)/ticksPerQuarter, req.Beats)
+		}
+	}
+	return req, nil
+}
+
+// MidiValidationResponse is returned by POST /api/midi?validate=true: whether
+// the request would render, why not, and the settings it would render with.
+type MidiValidationResponse struct {
+	Valid   bool             `json:"valid"`
+	Errors  []string         `json:"errors"`
+	Applied *AppliedSettings `json:"applied,omitempty"` // only when valid
+}
+
+// validateMidi answers POST /api/midi?validate=true without rendering anything.
+func validateMidi(c *gin.Context) {
+	var req MidiRequest
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		req, err = validateMidiRequest(c, req)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, MidiValidationResponse{Errors: []string{err.Error()}})
+		return
+	}
+	applied := appliedSettings(req)
+	c.JSON(http.StatusOK, MidiValidationResponse{Valid: true, Errors: []string{}, Applied: &applied})
+}
+
+// GenerateMidi handles POST /api/midi. With ?validate=true it only checks the
+// request, answering with a MidiValidationResponse.
+func GenerateMidi(c *gin.Context) {
+	if c.Query("validate") == "true" {
+		validateMidi(c)
+		return
+	}
+	req, ok := bindMidiRequest(c)
+	if !ok {
+		return
+	}
 
-	// Validate pattern name
-	if !validPatterns[req.Pattern] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown pattern: " + req.Pattern})
+	midi := buildMidi(req)
+
+	requestLogger(c).Info("midi generated",
+		"pattern", req.Pattern,
+		"chords", len(req.Chords),
+		"tempo", req.Tempo,
+		"bytes", len(midi),
+	)
+
+	if req.Format == "json" {
+		warnings := append(chordWarnings(req), swingWarnings(req)...)
+		resp := MidiJSONResponse{
+			Midi:       midi,
+			Warnings:   warnings,
+			Fingerings: chordFingerings(req),
+			RootShift:  req.rootShift,
+			Applied:    appliedSettings(req),
+		}
+		if req.Stems {
+			resp.Stems = buildStems(req)
+		}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=\"progression.mid\"")
+	c.Data(http.StatusOK, "audio/midi", midi)
+}
+
+// variantRequest renders one chord diagram as a one-bar request: fretted
+// instruments play the variant's frets, piano plays its keys.
+func variantRequest(inst models.Instrument, chord string, v models.ChordVariant, pattern string) MidiRequest {
+	req := MidiRequest{
+		Chords:  []string{chord},
+		Tempo:   120,
+		Pattern: pattern,
+		Octave:  4,
+		Beats:   4,
+	}
+	if len(inst.OpenMidi) > 0 && len(v.Frets) > 0 {
+		req.OpenMidi = inst.OpenMidi
+		req.Frets = [][]string{v.Frets}
+	} else if len(v.Keys) > 0 {
+		req.Keys = [][]string{v.Keys}
+	}
+	return req
+}
+
+// GetChordMidi renders a single chord diagram as a one-bar MIDI file for quick
+// audition: GET /api/chords/:instrument/:chord/midi?pattern=…&variant=….
+// Fretted instruments play the variant's frets; piano plays its keys.
+func GetChordMidi(c *gin.Context) {
+	instrument := c.Param("instrument")
+	inst, err := findInstrument(instrument)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	diagrams, err := loadChordDiagrams(inst.Key)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pattern := c.DefaultQuery("pattern", "whole")
+	// "custom" and "strum-dsl" need a rhythm or strum string, which a query string doesn't carry.
+	if !validPatterns[pattern] || pattern == "custom" || pattern == "strum-dsl" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown pattern: " + pattern})
+		return
+	}
+
+	chord := normalizeChordName(c.Param("chord"))
+	variants := diagrams[chord]
+	if len(variants) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no %s diagram for chord: %s", inst.Key, c.Param("chord"))})
+		return
+	}
+	variant, err := strconv.Atoi(c.DefaultQuery("variant", "0"))
+	if err != nil || variant < 0 || variant >= len(variants) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("variant must be in range 0–%d", len(variants)-1)})
+		return
+	}
+
+	midi := buildMidi(variantRequest(inst, chord, variants[variant], pattern))
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", chord+".mid"))
+	c.Data(http.StatusOK, "audio/midi", midi)
+}
+
+// GetChordMidiAll handles GET /api/chords/:instrument/:chord/midi-all,
+// returning one base64 MIDI clip per variant, each a single strummed chord,
+// in the diagram order.
+func GetChordMidiAll(c *gin.Context) {
+	inst, err := findInstrument(c.Param("instrument"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	diagrams, err := loadChordDiagrams(inst.Key)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	chord := normalizeChordName(c.Param("chord"))
+	variants := diagrams[chord]
+	if len(variants) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no %s diagram for chord: %s", inst.Key, c.Param("chord"))})
+		return
+	}
+
+	clips := make([][]byte, len(variants))
+	for i, v := range variants {
+		clips[i] = buildMidi(variantRequest(inst, chord, v, "whole"))
+	}
+	c.JSON(http.StatusOK, clips)
+}
+
+// GetProgressionMidi handles GET /api/progressions/:name/midi, rendering a
+// curated progression in its original key:
+// ?pattern=…&tempo=…&instrument=…. With an instrument each chord plays its
+// first diagram voicing; otherwise chord-quality voicings are used.
+func GetProgressionMidi(c *gin.Context) {
+	prog, ok, err := findProgression(c.Param("name"))
+	if err != nil {
+		requestLogger(c).Error("could not load progressions", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load progressions"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown progression: " + c.Param("name")})
 		return
 	}
 
+	pattern := c.DefaultQuery("pattern", "quarter")
+	// "custom" and "strum-dsl" need a rhythm or strum string, which a query string doesn't carry.
+	if !validPatterns[pattern] || pattern == "custom" || pattern == "strum-dsl" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown pattern: " + pattern})
+		return
+	}
+	tempo, err := strconv.Atoi(c.DefaultQuery("tempo", "120"))
+	if err != nil || tempo < 1 || tempo > 300 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tempo must be in range 1–300"})
+		return
+	}
+
+	req := MidiRequest{
+		Chords:  prog.Chords,
+		Tempo:   tempo,
+		Pattern: pattern,
+		Octave:  4,
+		Beats:   4,
+	}
+	if key := c.Query("instrument"); key != "" {
+		inst, err := findInstrument(key)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		diagrams, err := loadChordDiagrams(inst.Key)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// Chords without a diagram keep an empty row and fall back to
+		// chord-quality notes.
+		req.Frets = make([][]string, len(req.Chords))
+		req.Keys = make([][]string, len(req.Chords))
+		for i, chord := range req.Chords {
+			if variants := diagrams[normalizeChordName(chord)]; len(variants) > 0 {
+				req.Frets[i], req.Keys[i] = variants[0].Frets, variants[0].Keys
+			}
+		}
+		req.OpenMidi = inst.OpenMidi
+	}
+
 	midi := buildMidi(req)
 
-	log.Printf("midi: generated %d bytes for %d chords pattern=%s tempo=%d", len(midi), len(req.Chords), req.Pattern, req.Tempo)
+	requestLogger(c).Info("progression midi generated",
+		"progression", prog.Name,
+		"pattern", pattern,
+		"bytes", len(midi),
+	)
 
 	c.Header("Content-Disposition", "attachment; filename=\"progression.mid\"")
 	c.Data(http.StatusOK, "audio/midi", midi)
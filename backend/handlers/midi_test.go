@@ -3,6 +3,9 @@ package handlers
 import (
 	"bytes"
 	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -76,6 +79,16 @@ func TestTransposeChord(t *testing.T) {
 		{"Am", 3, "Cm"},
 		{"F#m7", 6, "Cm7"},
 		{"Bb", 2, "C"},
+		// Slash chords: the bass moves by the same interval, so an
+		// inversion keeps its chord tone in the bass.
+		{"C/E", 5, "F/A"},
+		{"C/E", 7, "G/B"},
+		{"C/E", -1, "B/D#"},
+		{"D/F#", 2, "E/G#"},
+		{"D/F#", -2, "C/E"},
+		{"D/F#", 12, "D/F#"},
+		{"Am7/G", 3, "Cm7/A#"},
+		{"C/x", 2, "D/x"}, // not a note: left alone
 	}
 	for _, tc := range cases {
 		got := transposeChord(tc.chord, tc.semitones)
@@ -106,6 +119,25 @@ func TestGetTransposition(t *testing.T) {
 	}
 }
 
+func TestGetTransposition_FlatKeys(t *testing.T) {
+	cases := []struct {
+		from, to string
+		want     int
+	}{
+		{"C", "Gb", 6},
+		{"Db", "F#", 5},
+		{"Bb", "Eb", 5},
+		{"Ab", "G#", 0},
+		{"Eb", "C", 9},
+	}
+	for _, tc := range cases {
+		got := getTransposition(tc.from, tc.to)
+		if got != tc.want {
+			t.Errorf("getTransposition(%q, %q) = %d, want %d", tc.from, tc.to, got, tc.want)
+		}
+	}
+}
+
 // ── fretsToMidi ───────────────────────────────────────────────────────────
 
 func TestFretsToMidi_Basic(t *testing.T) {
@@ -141,6 +173,84 @@ func TestFretsToMidi_OutOfRange(t *testing.T) {
 	}
 }
 
+func TestTuning_PartialCapo(t *testing.T) {
+	// Esus4-style partial capo at fret 2 across the A, D and G strings.
+	req := MidiRequest{OpenMidi: []int{40, 45, 50, 55, 59, 64}, Capo: 2, CapoStrings: []int{1, 2, 3}}
+	if got, want := tuning(req), []int{40, 47, 52, 57, 59, 64}; !reflect.DeepEqual(got, want) {
+		t.Errorf("partial capo tuning = %v, want %v", got, want)
+	}
+	if req.OpenMidi[1] != 45 {
+		t.Error("tuning modified the request's openMidi")
+	}
+
+	// Without capoStrings the capo covers every string.
+	req.CapoStrings = nil
+	if got, want := tuning(req), []int{42, 47, 52, 57, 61, 66}; !reflect.DeepEqual(got, want) {
+		t.Errorf("full capo tuning = %v, want %v", got, want)
+	}
+
+	// An E shape behind the partial capo: only the covered strings sound higher.
+	req = MidiRequest{
+		Chords:      []string{"E"},
+		Tempo:       120,
+		Pattern:     "whole",
+		Beats:       4,
+		OpenMidi:    []int{40, 45, 50, 55, 59, 64},
+		Frets:       [][]string{{"0", "2", "2", "1", "0", "0"}},
+		Capo:        2,
+		CapoStrings: []int{1, 2, 3},
+	}
+	var ons []byte
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+			ons = append(ons, ev.data1)
+		}
+	}
+	if want := []byte{40, 49, 54, 58, 59, 64}; !bytes.Equal(ons, want) {
+		t.Errorf("note-ons = %v, want %v", ons, want)
+	}
+}
+
+func TestApplyTuningOverrides_DropD(t *testing.T) {
+	openMidi := []int{40, 45, 50, 55, 59, 64}
+	tuned := applyTuningOverrides(openMidi, map[int]int{0: 38})
+	if tuned[0] != 38 {
+		t.Errorf("string 0 = %d, want 38", tuned[0])
+	}
+	if openMidi[0] != 40 {
+		t.Error("applyTuningOverrides modified the base tuning")
+	}
+
+	// Open D5-style shape: only the low string's pitch should move
+	frets := []string{"0", "x", "0", "2", "3", "2"}
+	std := fretsToMidi(frets, openMidi)
+	drop := fretsToMidi(frets, tuned)
+	if drop[0] != std[0]-2 {
+		t.Errorf("low note = %d, want %d", drop[0], std[0]-2)
+	}
+	if !bytes.Equal(drop[1:], std[1:]) {
+		t.Errorf("upper notes changed: %v vs %v", drop[1:], std[1:])
+	}
+}
+
+func TestChordWarnings_ThinVoicing(t *testing.T) {
+	req := MidiRequest{
+		Chords:   []string{"C", "G"},
+		OpenMidi: []int{40, 45, 50, 55, 59, 64},
+		Frets: [][]string{
+			{"x", "3", "2", "0", "1", "0"},
+			{"x", "x", "x", "x", "3", "3"}, // all but two strings muted
+		},
+	}
+	warnings := chordWarnings(req)
+	if len(warnings) != 1 {
+		t.Fatalf("got %d warnings, want 1: %v", len(warnings), warnings)
+	}
+	if !strings.Contains(warnings[0], "chord 2 (G)") {
+		t.Errorf("warning %q should name chord 2 (G)", warnings[0])
+	}
+}
+
 // ── chordToMidi ───────────────────────────────────────────────────────────
 
 func TestChordToMidi_CMajor(t *testing.T) {
@@ -152,6 +262,30 @@ func TestChordToMidi_CMajor(t *testing.T) {
 	}
 }
 
+func TestCleanBass_RaisesLowThird(t *testing.T) {
+	// C2=36, E2=40, G2=43: the E is a muddy third above the bass.
+	if got, want := cleanBass(chordToMidi("C", 2)), []byte{36, 43, 52}; !bytes.Equal(got, want) {
+		t.Errorf("cleanBass(C2 triad) = %v, want %v", got, want)
+	}
+	// Above E3 the voicing is left alone.
+	if got, want := cleanBass(chordToMidi("C", 4)), []byte{60, 64, 67}; !bytes.Equal(got, want) {
+		t.Errorf("cleanBass(C4 triad) = %v, want %v", got, want)
+	}
+}
+
+func TestBuildMidi_CleanBass(t *testing.T) {
+	req := MidiRequest{Chords: []string{"C"}, Tempo: 120, Pattern: "whole", Octave: 2, Beats: 4, CleanBass: true}
+	var ons []byte
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+			ons = append(ons, ev.data1)
+		}
+	}
+	if want := []byte{36, 43, 52}; !bytes.Equal(ons, want) {
+		t.Errorf("note-ons = %v, want %v (E raised an octave)", ons, want)
+	}
+}
+
 func TestChordToMidi_AMinor(t *testing.T) {
 	got := chordToMidi("Am", 4)
 	// A4=69, C5=72, E5=76
@@ -179,6 +313,46 @@ func TestChordToMidi_UnknownChord(t *testing.T) {
 	}
 }
 
+// ── foldIntoRange ─────────────────────────────────────────────────────────
+
+func TestFoldIntoRange_HighChord(t *testing.T) {
+	notes := chordToMidi("Cmaj7", 7) // C8 E8 G8 B8 → 108+
+	got := foldIntoRange(notes, 48, 72)
+	if len(got) != len(notes) {
+		t.Errorf("folded %d notes into %d", len(notes), len(got))
+	}
+	for _, n := range got {
+		if n < 48 || n > 72 {
+			t.Errorf("note %d outside 48–72", n)
+		}
+	}
+}
+
+func TestFoldIntoRange_SortedAndDeduped(t *testing.T) {
+	got := foldIntoRange([]byte{36, 60, 76}, 60, 72)
+	want := []byte{60, 64}
+	if !bytes.Equal(got, want) {
+		t.Errorf("foldIntoRange = %v, want %v", got, want)
+	}
+}
+
+func TestBuildMidi_RangeApplied(t *testing.T) {
+	req := MidiRequest{
+		Chords:    []string{"Am7"},
+		Tempo:     120,
+		Pattern:   "whole",
+		Octave:    6,
+		Beats:     4,
+		RangeLow:  48,
+		RangeHigh: 72,
+	}
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 && (ev.data1 < 48 || ev.data1 > 72) {
+			t.Errorf("note %d outside requested range", ev.data1)
+		}
+	}
+}
+
 // ── noteAt / lowerOctave helpers ──────────────────────────────────────────
 
 func TestNoteAt(t *testing.T) {
@@ -194,6 +368,21 @@ func TestNoteAt(t *testing.T) {
 	}
 }
 
+func TestNoteClamped(t *testing.T) {
+	notes := []byte{60, 64, 67}
+	cases := []struct {
+		i    int
+		want byte
+	}{
+		{0, 60}, {2, 67}, {5, 67}, {-1, 67}, {-3, 60}, {-9, 60},
+	}
+	for _, tc := range cases {
+		if got := noteClamped(notes, tc.i); got != tc.want {
+			t.Errorf("noteClamped(notes, %d) = %d, want %d", tc.i, got, tc.want)
+		}
+	}
+}
+
 func TestLowerOctave(t *testing.T) {
 	if lowerOctave(60) != 48 {
 		t.Errorf("lowerOctave(60) = %d, want 48", lowerOctave(60))
@@ -206,6 +395,28 @@ func TestLowerOctave(t *testing.T) {
 	}
 }
 
+// ── thinNotes ─────────────────────────────────────────────────────────────
+
+func TestThinNotes_KeepsRootAndTop(t *testing.T) {
+	// Six-string G major voicing: G2 B2 D3 G3 B3 G4
+	notes := []byte{43, 47, 50, 55, 59, 67}
+	got := thinNotes(notes, 4)
+	want := []byte{43, 47, 59, 67}
+	if !bytes.Equal(got, want) {
+		t.Errorf("thinNotes(…, 4) = %v, want %v", got, want)
+	}
+}
+
+func TestThinNotes_NoCap(t *testing.T) {
+	notes := []byte{60, 64, 67}
+	if got := thinNotes(notes, 0); !bytes.Equal(got, notes) {
+		t.Errorf("thinNotes(…, 0) = %v, want unchanged", got)
+	}
+	if got := thinNotes(notes, 1); !bytes.Equal(got, []byte{60}) {
+		t.Errorf("thinNotes(…, 1) = %v, want [60]", got)
+	}
+}
+
 // ── buildMidi smoke tests ─────────────────────────────────────────────────
 
 // validMidiHeader checks the first 14 bytes of a MIDI file are a valid MThd.
@@ -226,6 +437,55 @@ func validMidiHeader(t *testing.T, midi []byte) {
 	}
 }
 
+// midiEvent is a decoded track event: delta time, status byte and up to two data bytes.
+// Meta events keep their type in data1.
+type midiEvent struct {
+	delta  uint32
+	status byte
+	data1  byte
+	data2  byte
+}
+
+// trackChunks splits a file produced by buildMidi into its MTrk data chunks.
+func trackChunks(t *testing.T, midi []byte) [][]byte {
+	t.Helper()
+	validMidiHeader(t, midi)
+	var chunks [][]byte
+	for i := 14; i+8 <= len(midi); {
+		if string(midi[i:i+4]) != "MTrk" {
+			t.Fatalf("expected MTrk at offset %d, got %q", i, midi[i:i+4])
+		}
+		n := int(binary.BigEndian.Uint32(midi[i+4 : i+8]))
+		chunks = append(chunks, midi[i+8:i+8+n])
+		i += 8 + n
+	}
+	return chunks
+}
+
+// decodeEvents decodes the events of one MTrk data chunk.
+func decodeEvents(trk []byte) []midiEvent {
+	var events []midiEvent
+	for _, ev := range parseTrack(trk) {
+		events = append(events, midiEvent{ev.delta, ev.status, ev.data1, ev.data2})
+	}
+	return events
+}
+
+// trackEvents decodes the first (chord) track of a file produced by buildMidi.
+func trackEvents(t *testing.T, midi []byte) []midiEvent {
+	t.Helper()
+	return decodeEvents(trackChunks(t, midi)[0])
+}
+
+// sumDeltas returns the total tick length of a decoded track.
+func sumDeltas(events []midiEvent) uint32 {
+	var total uint32
+	for _, ev := range events {
+		total += ev.delta
+	}
+	return total
+}
+
 func TestBuildMidi_Quarter(t *testing.T) {
 	req := MidiRequest{
 		Chords:  []string{"C", "Am", "F", "G"},
@@ -238,6 +498,50 @@ func TestBuildMidi_Quarter(t *testing.T) {
 	validMidiHeader(t, midi)
 }
 
+func TestParseTrack_QuarterRoundTrip(t *testing.T) {
+	req := MidiRequest{Chords: []string{"C"}, Tempo: 120, Pattern: "quarter", Octave: 4, Beats: 4}
+	events := parseTrack(trackChunks(t, buildMidi(req))[0])
+
+	// tempo + 4 beats × 3 notes × (on + off) + end of track
+	if len(events) != 1+4*3*2+1 {
+		t.Fatalf("decoded %d events, want %d", len(events), 1+4*3*2+1)
+	}
+	if tempo := events[0]; tempo.status != 0xFF || tempo.data1 != 0x51 || len(tempo.meta) != 3 {
+		t.Errorf("first event = %+v, want a 3-byte tempo meta event", tempo)
+	}
+	if eot := events[len(events)-1]; eot.status != 0xFF || eot.data1 != 0x2F || eot.tick != 4*ticksPerQuarter {
+		t.Errorf("last event = %+v, want end of track at tick %d", eot, 4*ticksPerQuarter)
+	}
+
+	// Every note-on is released one beat later by a matching note-off.
+	started := map[byte]uint32{}
+	var pairs int
+	for _, ev := range events[1 : len(events)-1] {
+		switch ev.status {
+		case 0x90:
+			if _, held := started[ev.data1]; held {
+				t.Fatalf("note %d struck again at tick %d while held", ev.data1, ev.tick)
+			}
+			started[ev.data1] = ev.tick
+		case 0x80:
+			on, held := started[ev.data1]
+			if !held {
+				t.Fatalf("note-off for %d at tick %d with no note-on", ev.data1, ev.tick)
+			}
+			if ev.tick-on != ticksPerQuarter {
+				t.Errorf("note %d held %d ticks, want %d", ev.data1, ev.tick-on, ticksPerQuarter)
+			}
+			delete(started, ev.data1)
+			pairs++
+		default:
+			t.Errorf("unexpected event %+v", ev)
+		}
+	}
+	if pairs != 12 || len(started) != 0 {
+		t.Errorf("got %d on/off pairs with %d notes left on, want 12 and 0", pairs, len(started))
+	}
+}
+
 func TestBuildMidi_AllPatterns(t *testing.T) {
 	chords := []string{"C", "G"}
 	for pattern := range validPatterns {
@@ -301,11 +605,1403 @@ func TestBuildMidi_FretsMode(t *testing.T) {
 	validMidiHeader(t, midi)
 }
 
-// ── validPatterns map ─────────────────────────────────────────────────────
+func TestBuildMidi_ArpeggioQuintuplets(t *testing.T) {
+	req := MidiRequest{
+		Chords:      []string{"Cmaj7"},
+		Tempo:       120,
+		Pattern:     "arpeggio",
+		Octave:      4,
+		Beats:       4,
+		Subdivision: 5,
+	}
+	events := trackEvents(t, buildMidi(req))
+	chordTicks := uint32(ticksPerQuarter * req.Beats)
+	if got := sumDeltas(events); got != chordTicks {
+		t.Errorf("summed deltas = %d, want %d", got, chordTicks)
+	}
+	noteOns := 0
+	for _, ev := range events {
+		if ev.status&0xF0 == 0x90 {
+			noteOns++
+		}
+	}
+	if noteOns != 5*req.Beats {
+		t.Errorf("note-ons = %d, want %d", noteOns, 5*req.Beats)
+	}
+}
 
-func TestValidPatterns_Count(t *testing.T) {
-	// There should be exactly 31 patterns (matches the UI selector count)
-	if len(validPatterns) != 31 {
-		t.Errorf("validPatterns has %d entries, want 31", len(validPatterns))
+func TestBuildMidi_ArpeggioSeptupletsExact(t *testing.T) {
+	// 480 / 7 leaves a remainder; the bar must still be exactly chordTicks long
+	req := MidiRequest{
+		Chords:      []string{"C", "G"},
+		Tempo:       120,
+		Pattern:     "arpeggio",
+		Octave:      4,
+		Beats:       3,
+		Subdivision: 7,
+	}
+	events := trackEvents(t, buildMidi(req))
+	want := uint32(ticksPerQuarter * req.Beats * len(req.Chords))
+	if got := sumDeltas(events); got != want {
+		t.Errorf("summed deltas = %d, want %d", got, want)
+	}
+}
+
+func TestBuildMidi_ArpSequence(t *testing.T) {
+	req := MidiRequest{
+		Chords:      []string{"C"},
+		Tempo:       120,
+		Pattern:     "arpeggio",
+		Octave:      4,
+		Beats:       4,
+		ArpSequence: []int{0, 2, 1, 2},
+	}
+	var got []byte
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		if ev.status&0xF0 == 0x90 {
+			got = append(got, ev.data1)
+		}
+	}
+	// C E G → indices 0,2,1,2 cycled over 8 eighths
+	want := []byte{60, 67, 64, 67, 60, 67, 64, 67}
+	if !bytes.Equal(got, want) {
+		t.Errorf("note order = %v, want %v", got, want)
+	}
+}
+
+func TestBuildMidi_CustomRhythm(t *testing.T) {
+	req := MidiRequest{
+		Chords:  []string{"C"},
+		Tempo:   120,
+		Pattern: "custom",
+		Octave:  4,
+		Beats:   4,
+		Rhythm:  []string{"q", "e", "e", "h"},
+	}
+	// Non-zero deltas mark each hit's release: q, e, e, h
+	var got []uint32
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		if ev.delta > 0 {
+			got = append(got, ev.delta)
+		}
+	}
+	want := []uint32{480, 240, 240, 960}
+	if len(got) != len(want) {
+		t.Fatalf("deltas = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("delta[%d] = %d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRhythmTicks(t *testing.T) {
+	durs, err := rhythmTicks([]string{"h.", "s", "s", "e"})
+	if err != nil {
+		t.Fatalf("rhythmTicks returned error: %v", err)
+	}
+	want := []uint32{1440, 120, 120, 240}
+	for i := range want {
+		if durs[i] != want[i] {
+			t.Errorf("durs[%d] = %d, want %d", i, durs[i], want[i])
+		}
+	}
+	if _, err := rhythmTicks([]string{"q", "x"}); err == nil {
+		t.Error("unknown note value should return an error")
+	}
+}
+
+func TestBuildMidi_IntroStrum(t *testing.T) {
+	openMidi := []int{40, 45, 50, 55, 59, 64}
+	req := MidiRequest{
+		Chords:     []string{"C"},
+		Tempo:      120,
+		Pattern:    "whole",
+		Octave:     4,
+		Beats:      4,
+		OpenMidi:   openMidi,
+		Frets:      [][]string{{"x", "3", "2", "0", "1", "0"}},
+		IntroStrum: true,
+	}
+	var noteOns []midiEvent
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		if ev.status&0xF0 == 0x90 {
+			noteOns = append(noteOns, ev)
+		}
+	}
+	if len(noteOns) < len(openMidi) {
+		t.Fatalf("got %d note-ons, want at least %d", len(noteOns), len(openMidi))
+	}
+	// The first note-ons are the open strings, rolled low to high
+	for i, m := range openMidi {
+		if noteOns[i].data1 != byte(m) {
+			t.Errorf("intro note %d = %d, want %d", i, noteOns[i].data1, m)
+		}
+		if i > 0 && noteOns[i].delta != introStrumSpread {
+			t.Errorf("intro note %d delta = %d, want %d", i, noteOns[i].delta, introStrumSpread)
+		}
+	}
+	// The chord itself (C from frets, lowest note C3=48) follows the intro
+	if noteOns[len(openMidi)].data1 != 48 {
+		t.Errorf("first chord note = %d, want 48", noteOns[len(openMidi)].data1)
+	}
+}
+
+func TestBuildMidi_IntroStrumNeedsOpenMidi(t *testing.T) {
+	req := MidiRequest{
+		Chords:     []string{"C"},
+		Tempo:      120,
+		Pattern:    "whole",
+		Octave:     4,
+		Beats:      4,
+		IntroStrum: true,
+	}
+	with := buildMidi(req)
+	req.IntroStrum = false
+	if without := buildMidi(req); !bytes.Equal(with, without) {
+		t.Error("introStrum without openMidi should not change the output")
+	}
+}
+
+func TestNoteOffEvent_ReleaseVelocity(t *testing.T) {
+	got := noteOffEvent(0, 0, 60, 64)
+	want := []byte{0x00, 0x80, 60, 64}
+	if !bytes.Equal(got, want) {
+		t.Errorf("noteOffEvent = %v, want %v", got, want)
+	}
+}
+
+func TestBuildMidi_ReleaseVelocity(t *testing.T) {
+	req := MidiRequest{
+		Chords:          []string{"C"},
+		Tempo:           120,
+		Pattern:         "whole",
+		Octave:          4,
+		Beats:           4,
+		ReleaseVelocity: 70,
+	}
+	offs := 0
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		if ev.status&0xF0 == 0x80 {
+			offs++
+			if ev.data2 != 70 {
+				t.Errorf("note-off velocity = %d, want 70", ev.data2)
+			}
+		}
+	}
+	if offs == 0 {
+		t.Error("no note-off events found")
+	}
+}
+
+func TestBuildMidi_PatternSequence(t *testing.T) {
+	req := MidiRequest{
+		Chords:          []string{"C", "G", "Am"},
+		Tempo:           120,
+		Pattern:         "quarter",
+		Octave:          4,
+		Beats:           4,
+		PatternSequence: []string{"whole", "quarter"},
+	}
+	// Count note-ons per bar: whole = 1 hit × 3 notes, quarter = 4 hits × 3 notes
+	chordTicks := uint32(ticksPerQuarter * req.Beats)
+	perBar := make([]int, len(req.Chords))
+	var now uint32
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		now += ev.delta
+		if ev.status&0xF0 == 0x90 {
+			perBar[now/chordTicks]++
+		}
+	}
+	want := []int{3, 12, 3} // sequence cycles back to "whole" on the third chord
+	for i := range want {
+		if perBar[i] != want[i] {
+			t.Errorf("bar %d note-ons = %d, want %d", i, perBar[i], want[i])
+		}
+	}
+}
+
+// onsets returns the absolute tick of every sounding note-on (rest placeholders excluded).
+func onsets(events []midiEvent) []uint32 {
+	var out []uint32
+	var now uint32
+	for _, ev := range events {
+		now += ev.delta
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+			out = append(out, now)
+		}
+	}
+	return out
+}
+
+func TestBuildMidi_JazzSwingDelay(t *testing.T) {
+	req := MidiRequest{
+		Chords:  []string{"C"},
+		Tempo:   120,
+		Pattern: "jazz-swing",
+		Octave:  4,
+		Beats:   4,
+	}
+	straight := onsets(trackEvents(t, buildMidi(req)))
+	req.SwingDelay = 40
+	events := trackEvents(t, buildMidi(req))
+	swung := onsets(events)
+
+	// Notes 0-2 are the downbeat hit, 3-5 the 2-and hit
+	if swung[0] != straight[0] {
+		t.Errorf("downbeat moved: %d → %d", straight[0], swung[0])
+	}
+	if swung[3] != straight[3]+40 {
+		t.Errorf("off-beat onset = %d, want %d", swung[3], straight[3]+40)
+	}
+	if got := sumDeltas(events); got != uint32(ticksPerQuarter*req.Beats) {
+		t.Errorf("bar length = %d, want %d", got, ticksPerQuarter*req.Beats)
+	}
+}
+
+func TestBuildMidi_BluesShuffleSwingDelay(t *testing.T) {
+	req := MidiRequest{
+		Chords:     []string{"E7"},
+		Tempo:      100,
+		Pattern:    "blues-shuffle",
+		Octave:     3,
+		Beats:      4,
+		SwingDelay: 30,
+	}
+	events := trackEvents(t, buildMidi(req))
+	got := onsets(events)
+	// 4 notes per hit; hit 0 downbeat at 0, hit 1 upbeat at 320+30
+	if got[0] != 0 || got[4] != 350 {
+		t.Errorf("onsets = %d, %d; want 0, 350", got[0], got[4])
+	}
+	if total := sumDeltas(events); total != uint32(ticksPerQuarter*req.Beats) {
+		t.Errorf("bar length = %d, want %d", total, ticksPerQuarter*req.Beats)
+	}
+}
+
+func TestBuildMidi_PushEighths(t *testing.T) {
+	req := MidiRequest{
+		Chords:      []string{"C", "G"},
+		Tempo:       120,
+		Pattern:     "whole",
+		Octave:      4,
+		Beats:       4,
+		PushEighths: 1,
+	}
+	events := trackEvents(t, buildMidi(req))
+	chordTicks := uint32(ticksPerQuarter * req.Beats)
+	got := onsets(events)
+	// C C E G lands at 0; G B D is pushed an eighth ahead of the bar line
+	if got[0] != 0 {
+		t.Errorf("first chord onset = %d, want 0", got[0])
+	}
+	if want := chordTicks - ticksPerQuarter/2; got[3] != want {
+		t.Errorf("pushed chord onset = %d, want %d", got[3], want)
+	}
+	if total := sumDeltas(events); total != 2*chordTicks {
+		t.Errorf("track length = %d, want %d", total, 2*chordTicks)
+	}
+}
+
+func TestTruncateSlot_ReleasesHeldNotes(t *testing.T) {
+	var slot []byte
+	slot = append(slot, noteOnEvent(0, 0, 60, 100)...)
+	slot = append(slot, noteOnEvent(0, 0, 64, 100)...)
+	slot = append(slot, noteOffEvent(960, 0, 60, 0)...)
+	slot = append(slot, noteOffEvent(0, 0, 64, 0)...)
+
+	var want []byte
+	want = append(want, noteOnEvent(0, 0, 60, 100)...)
+	want = append(want, noteOnEvent(0, 0, 64, 100)...)
+	want = append(want, noteOffEvent(720, 0, 60, 0)...)
+	want = append(want, noteOffEvent(0, 0, 64, 0)...)
+	if got := truncateSlot(slot, 720, 0); !bytes.Equal(got, want) {
+		t.Errorf("truncateSlot = %v, want %v", got, want)
+	}
+}
+
+// drumBars returns the channel-10 note-ons of a drum track grouped by bar,
+// with ticks relative to the bar start.
+func drumBars(events []midiEvent, barTicks uint32) [][]midiEvent {
+	var bars [][]midiEvent
+	var now uint32
+	for _, ev := range events {
+		now += ev.delta
+		if ev.status != 0x90|drumChannel || ev.data2 == 0 {
+			continue
+		}
+		bar := int(now / barTicks)
+		for len(bars) <= bar {
+			bars = append(bars, nil)
+		}
+		ev.delta = now % barTicks
+		bars[bar] = append(bars[bar], ev)
+	}
+	return bars
+}
+
+func TestBuildMidi_DrumsAddSecondTrack(t *testing.T) {
+	req := MidiRequest{
+		Chords:  []string{"C", "G"},
+		Tempo:   120,
+		Pattern: "whole",
+		Octave:  4,
+		Beats:   4,
+		Drums:   true,
+	}
+	midi := buildMidi(req)
+	if format := binary.BigEndian.Uint16(midi[8:10]); format != 1 {
+		t.Errorf("format = %d, want 1", format)
+	}
+	chunks := trackChunks(t, midi)
+	if len(chunks) != 2 {
+		t.Fatalf("got %d tracks, want 2", len(chunks))
+	}
+	drums := decodeEvents(chunks[1])
+	if got, want := sumDeltas(drums), uint32(2*ticksPerQuarter*req.Beats); got != want {
+		t.Errorf("drum track length = %d, want %d", got, want)
+	}
+	for _, ev := range decodeEvents(chunks[0]) {
+		if ev.status != 0xFF && ev.status&0x0F == drumChannel {
+			t.Errorf("chord track has an event on the drum channel: %+v", ev)
+		}
+	}
+}
+
+func TestGMDrums_Names(t *testing.T) {
+	want := map[string]byte{"kick": 36, "snare": 38, "hihat-closed": 42, "rimshot": 37}
+	for name, note := range want {
+		if got, ok := gmDrums[name]; !ok || got != note {
+			t.Errorf("gmDrums[%q] = %d, want %d", name, got, note)
+		}
+	}
+}
+
+func TestBuildMidi_RimshotBackbeat(t *testing.T) {
+	req := MidiRequest{
+		Chords: []string{"C"}, Tempo: 120, Pattern: "whole", Octave: 4, Beats: 4,
+		Drums: true, Backbeat: "rimshot",
+	}
+	var rims, snares int
+	for _, ev := range parseTrack(trackChunks(t, buildMidi(req))[1]) {
+		if ev.status != 0x90|drumChannel || ev.data2 == 0 {
+			continue
+		}
+		switch ev.data1 {
+		case gmRimshot:
+			rims++
+			if beat := ev.tick / ticksPerQuarter; beat%2 != 1 || ev.tick%ticksPerQuarter != 0 {
+				t.Errorf("rimshot at tick %d, want only on beats 2 and 4", ev.tick)
+			}
+		case gmSnare:
+			snares++
+		}
+	}
+	if rims != 2 || snares != 0 {
+		t.Errorf("got %d rimshots and %d snares, want 2 and 0", rims, snares)
+	}
+}
+
+func TestBuildMidi_PerStringChannelNeverDrums(t *testing.T) {
+	// A 12-string tuning needs channels past 9, which must be skipped.
+	openMidi := []int{40, 52, 45, 57, 50, 62, 55, 67, 59, 59, 64, 64}
+	frets := make([]string, len(openMidi))
+	for i := range frets {
+		frets[i] = "0"
+	}
+	req := MidiRequest{
+		Chords: []string{"E"}, Tempo: 120, Pattern: "quarter", Octave: 4, Beats: 4,
+		OpenMidi: openMidi, Frets: [][]string{frets}, PerStringChannel: true,
+	}
+	for _, ev := range parseTrack(trackChunks(t, buildMidi(req))[0]) {
+		if ev.status != 0xFF && ev.status&0x0F == drumChannel {
+			t.Errorf("string note on the drum channel: %+v", ev)
+		}
+	}
+}
+
+func TestBuildMidi_FillLastBar(t *testing.T) {
+	req := MidiRequest{
+		Chords:      []string{"C", "Am", "F", "G"},
+		Tempo:       120,
+		Pattern:     "whole",
+		Octave:      4,
+		Beats:       4,
+		Drums:       true,
+		FillLastBar: true,
+	}
+	barTicks := uint32(ticksPerQuarter * req.Beats)
+	bars := drumBars(decodeEvents(trackChunks(t, buildMidi(req))[1]), barTicks)
+	if len(bars) != 4 {
+		t.Fatalf("got %d drum bars, want 4", len(bars))
+	}
+	same := func(a, b []midiEvent) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+	for i := 1; i < 3; i++ {
+		if !same(bars[0], bars[i]) {
+			t.Errorf("groove bar %d differs from bar 0", i)
+		}
+	}
+	if same(bars[2], bars[3]) {
+		t.Error("last bar should be a fill, but matches the groove")
+	}
+}
+
+func TestBuildMidi_FillNeedsDrums(t *testing.T) {
+	req := MidiRequest{
+		Chords:      []string{"C", "G"},
+		Tempo:       120,
+		Pattern:     "whole",
+		Octave:      4,
+		Beats:       4,
+		FillLastBar: true,
+	}
+	if n := len(trackChunks(t, buildMidi(req))); n != 1 {
+		t.Errorf("fillLastBar without drums produced %d tracks, want 1", n)
+	}
+}
+
+func TestBuildMidi_ClickSubdivision(t *testing.T) {
+	cases := []struct {
+		sub  string
+		step uint32
+	}{
+		{"quarter", ticksPerQuarter},
+		{"eighth", ticksPerQuarter / 2},
+	}
+	for _, tc := range cases {
+		t.Run(tc.sub, func(t *testing.T) {
+			req := MidiRequest{
+				Chords:           []string{"C", "G"},
+				Tempo:            120,
+				Pattern:          "whole",
+				Octave:           4,
+				Beats:            4,
+				ClickSubdivision: tc.sub,
+			}
+			chunks := trackChunks(t, buildMidi(req))
+			if len(chunks) != 2 {
+				t.Fatalf("got %d tracks, want 2", len(chunks))
+			}
+			var clicks []uint32
+			var now uint32
+			for _, ev := range decodeEvents(chunks[1]) {
+				now += ev.delta
+				if ev.status == 0x90|drumChannel && ev.data2 > 0 {
+					clicks = append(clicks, now)
+				}
+			}
+			total := uint32(2 * ticksPerQuarter * req.Beats)
+			if want := int(total / tc.step); len(clicks) != want {
+				t.Fatalf("got %d clicks, want %d", len(clicks), want)
+			}
+			for i, at := range clicks {
+				if at != uint32(i)*tc.step {
+					t.Errorf("click %d at tick %d, want %d", i, at, uint32(i)*tc.step)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildMidi_ClickDropAfter(t *testing.T) {
+	req := MidiRequest{
+		Chords:           []string{"C", "G"},
+		Tempo:            120,
+		Pattern:          "whole",
+		Octave:           4,
+		Beats:            4,
+		Repeat:           3,
+		Drums:            true,
+		ClickSubdivision: "quarter",
+		ClickDropAfter:   1,
+	}
+	passTicks := uint32(2 * 4 * ticksPerQuarter)
+	var clicks, drums int
+	for _, chunk := range trackChunks(t, buildMidi(req))[1:] {
+		var now uint32
+		for _, ev := range decodeEvents(chunk) {
+			now += ev.delta
+			if ev.status != 0x90|drumChannel || ev.data2 == 0 {
+				continue
+			}
+			switch ev.data1 {
+			case gmMetronomeClick, gmMetronomeBell:
+				clicks++
+				if now >= passTicks {
+					t.Errorf("click at tick %d, after the first pass ends at %d", now, passTicks)
+				}
+			default:
+				drums++
+			}
+		}
+	}
+	if clicks != 8 {
+		t.Errorf("got %d clicks, want 8 (one pass of quarters)", clicks)
+	}
+	if drums == 0 {
+		t.Error("drums dropped out with the click")
+	}
+	// The click track still lasts the whole song.
+	chunks := trackChunks(t, buildMidi(req))
+	if got, want := sumDeltas(decodeEvents(chunks[len(chunks)-1])), 3*passTicks; got != want {
+		t.Errorf("click track length = %d ticks, want %d", got, want)
+	}
+}
+
+func TestBuildMidi_MarkerTrack(t *testing.T) {
+	req := MidiRequest{
+		Chords:          []string{"C", "Am"},
+		chordBeats:      []int{0, 2},
+		Tempo:           120,
+		Pattern:         "quarter",
+		Octave:          4,
+		Beats:           4,
+		Repeat:          2,
+		RepeatTranspose: 2,
+		Drums:           true,
+		MarkerTrack:     true,
+	}
+	chunks := trackChunks(t, buildMidi(req))
+	if len(chunks) != 3 {
+		t.Fatalf("got %d tracks, want 3 (chords, drums, markers)", len(chunks))
+	}
+	var names []string
+	var ticks []uint32
+	for _, ev := range parseTrack(chunks[2]) {
+		if ev.status != 0xFF {
+			t.Fatalf("marker track has non-meta event %#x", ev.status)
+		}
+		if ev.data1 == 0x06 {
+			names = append(names, string(ev.meta))
+			ticks = append(ticks, ev.tick)
+		}
+	}
+	wantNames := []string{"C", "Am", "D", "Bm"}
+	wantTicks := []uint32{0, 4 * ticksPerQuarter, 6 * ticksPerQuarter, 10 * ticksPerQuarter}
+	if !reflect.DeepEqual(names, wantNames) || !reflect.DeepEqual(ticks, wantTicks) {
+		t.Errorf("markers = %v at %v, want %v at %v", names, ticks, wantNames, wantTicks)
+	}
+	// The chord track keeps no markers of its own.
+	for _, ev := range parseTrack(chunks[0]) {
+		if ev.status == 0xFF && ev.data1 == 0x06 {
+			t.Errorf("chord track has marker %q", ev.meta)
+		}
+	}
+	if got, want := sumDeltas(decodeEvents(chunks[2])), sumDeltas(decodeEvents(chunks[0])); got != want {
+		t.Errorf("marker track length = %d ticks, want %d", got, want)
+	}
+}
+
+func TestBuildMidi_Rubato(t *testing.T) {
+	req := MidiRequest{
+		Chords:  []string{"C", "Am", "F", "G"},
+		Tempo:   100,
+		Pattern: "whole",
+		Octave:  4,
+		Beats:   4,
+		Repeat:  2,
+		Rubato:  0.1,
+	}
+	events := parseTrack(trackChunks(t, buildMidi(req))[0])
+	var bpms []int
+	var faster, slower bool
+	for _, ev := range events {
+		if ev.status != 0xFF || ev.data1 != 0x51 {
+			continue
+		}
+		if ev.tick%(4*ticksPerQuarter) != 0 {
+			t.Errorf("tempo change at tick %d, not on a bar line", ev.tick)
+		}
+		uspq := int(ev.meta[0])<<16 | int(ev.meta[1])<<8 | int(ev.meta[2])
+		bpm := int(math.Round(60_000_000 / float64(uspq)))
+		if bpm < 90 || bpm > 110 {
+			t.Errorf("tempo %d strays beyond 10%% of 100", bpm)
+		}
+		faster = faster || bpm > 100
+		slower = slower || bpm < 100
+		bpms = append(bpms, bpm)
+	}
+	if len(bpms) != 8 || !faster || !slower {
+		t.Errorf("tempos = %v, want one per bar swelling above and below 100", bpms)
+	}
+	if got, want := sumDeltas(decodeEvents(trackChunks(t, buildMidi(req))[0])), uint32(8*4*ticksPerQuarter); got != want {
+		t.Errorf("length = %d ticks, want %d", got, want)
+	}
+}
+
+func TestBuildMidi_GraceNotes(t *testing.T) {
+	req := MidiRequest{
+		Chords:     []string{"C", "F", "G"},
+		Tempo:      120,
+		Pattern:    "quarter",
+		Octave:     4,
+		Beats:      4,
+		GraceNotes: true,
+	}
+	events := parseTrack(trackChunks(t, buildMidi(req))[0])
+	bar := uint32(4 * ticksPerQuarter)
+	var graces []trackEvent
+	for _, ev := range events {
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 && ev.tick%ticksPerQuarter != 0 {
+			graces = append(graces, ev)
+		}
+	}
+	if len(graces) != 2 {
+		t.Fatalf("got %d off-beat note-ons, want a grace note before each of the 2 chord changes", len(graces))
+	}
+	// F4 (65) and G4 (67) are the bass notes of F and G at octave 4.
+	for i, want := range []struct {
+		tick uint32
+		note byte
+	}{{bar - graceTicks, 64}, {2*bar - graceTicks, 66}} {
+		g := graces[i]
+		if g.tick != want.tick || g.data1 != want.note || g.data2 >= 100 {
+			t.Errorf("grace %d = note %d vel %d at tick %d, want soft note %d at %d", i, g.data1, g.data2, g.tick, want.note, want.tick)
+		}
+	}
+	if got, want := sumDeltas(decodeEvents(trackChunks(t, buildMidi(req))[0])), 3*bar; got != want {
+		t.Errorf("length = %d ticks, want %d", got, want)
+	}
+}
+
+func TestBuildMidi_RepeatTranspose(t *testing.T) {
+	req := MidiRequest{
+		Chords:          []string{"C", "F"},
+		Tempo:           120,
+		Pattern:         "whole",
+		Octave:          4,
+		Beats:           4,
+		Repeat:          2,
+		RepeatTranspose: 2,
+	}
+	var notes []byte
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+			notes = append(notes, ev.data1)
+		}
+	}
+	// Two passes × two chords × three notes
+	if len(notes) != 12 {
+		t.Fatalf("got %d note-ons, want 12", len(notes))
+	}
+	for i := 0; i < 6; i++ {
+		if notes[i+6] != notes[i]+2 {
+			t.Errorf("second pass note %d = %d, want %d", i, notes[i+6], notes[i]+2)
+		}
+	}
+}
+
+func TestShiftNotes_StaysInRange(t *testing.T) {
+	got := shiftNotes([]byte{120, 125}, 5)
+	for _, n := range got {
+		if n > 127 {
+			t.Errorf("shiftNotes produced out-of-range note %d", n)
+		}
+	}
+	if got[0] != 125 || got[1] != 118 {
+		t.Errorf("shiftNotes = %v, want [125 118]", got)
+	}
+}
+
+func TestBuildMidi_FinalHold(t *testing.T) {
+	req := MidiRequest{
+		Chords:    []string{"C", "F", "G"},
+		Tempo:     120,
+		Pattern:   "quarter",
+		Octave:    4,
+		Beats:     4,
+		FinalHold: true,
+	}
+	var offDeltas []uint32
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		if ev.status&0xF0 == 0x80 && ev.delta > 0 {
+			offDeltas = append(offDeltas, ev.delta)
+		}
+	}
+	if len(offDeltas) == 0 {
+		t.Fatal("no note-off events found")
+	}
+	last := offDeltas[len(offDeltas)-1]
+	if last != 4*ticksPerQuarter {
+		t.Errorf("final chord note-off delta = %d, want %d", last, 4*ticksPerQuarter)
+	}
+	for i, d := range offDeltas[:len(offDeltas)-1] {
+		if d >= last {
+			t.Errorf("note-off delta %d = %d, want shorter than final hold %d", i, d, last)
+		}
+	}
+}
+
+func TestBuildMidi_TempoMap(t *testing.T) {
+	req := MidiRequest{
+		Chords:  []string{"C", "Am", "F", "G"},
+		Tempo:   80,
+		Pattern: "quarter",
+		Octave:  4,
+		Beats:   4,
+		TempoMap: []TempoChange{
+			{ChordIndex: 1, BPM: 100},
+			{ChordIndex: 3, BPM: 140},
+		},
+	}
+	var ticks []uint32
+	var tick uint32
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		tick += ev.delta
+		if ev.status == 0xFF && ev.data1 == 0x51 {
+			ticks = append(ticks, tick)
+		}
+	}
+	bar := uint32(4 * ticksPerQuarter)
+	want := []uint32{0, bar, 3 * bar}
+	if !reflect.DeepEqual(ticks, want) {
+		t.Errorf("tempo events at ticks %v, want %v", ticks, want)
+	}
+}
+
+func TestBuildMidi_OpenCentsPitchBend(t *testing.T) {
+	req := MidiRequest{
+		Chords:           []string{"C"},
+		Tempo:            120,
+		Pattern:          "whole",
+		Octave:           4,
+		Beats:            4,
+		OpenMidi:         []int{40, 45, 50, 55, 59, 64},
+		Frets:            [][]string{{"x", "3", "2", "0", "1", "0"}},
+		OpenCents:        []int{0, 0, 0, -14, 0, 10},
+		PerStringChannel: true,
+	}
+	// Pitch bend is per channel: a note hears the last bend sent on its
+	// channel at its tick, including bends written after its note-on.
+	current := map[byte]int{}
+	bends := map[byte]int{} // note → 14-bit bend in effect while it sounds
+	var struck []trackEvent // note-ons at the current tick
+	settle := func() {
+		for _, on := range struck {
+			bend, ok := current[on.status&0x0F]
+			if !ok {
+				bend = 8192
+			}
+			bends[on.data1] = bend
+		}
+		struck = nil
+	}
+	for _, ev := range parseTrack(trackChunks(t, buildMidi(req))[0]) {
+		if len(struck) > 0 && ev.tick != struck[0].tick {
+			settle()
+		}
+		switch ev.status & 0xF0 {
+		case 0xE0:
+			current[ev.status&0x0F] = int(ev.data2)<<7 | int(ev.data1)
+		case 0x90:
+			if ev.data2 > 0 && ev.data1 > 0 {
+				struck = append(struck, ev)
+			}
+		}
+	}
+	settle()
+	want := map[byte]int{
+		48: 8192,               // C3 on the A string
+		52: 8192,               // E3 on the D string
+		55: 8192 - 14*8192/200, // open G, 14 cents flat
+		60: 8192,               // C4 on the B string
+		64: 8192 + 10*8192/200, // open high E, 10 cents sharp
+	}
+	if !reflect.DeepEqual(bends, want) {
+		t.Errorf("pitch bends in effect = %v, want %v", bends, want)
+	}
+}
+
+func TestParseStrumDSL(t *testing.T) {
+	hits, err := parseStrumDSL("D-DU")
+	if err != nil {
+		t.Fatalf("parseStrumDSL: %v", err)
+	}
+	want := []strumHit{{active: true}, {}, {active: true}, {active: true, up: true}}
+	if !reflect.DeepEqual(hits, want) {
+		t.Errorf("parseStrumDSL = %+v, want %+v", hits, want)
+	}
+	if _, err := parseStrumDSL("D?"); err == nil {
+		t.Error("parseStrumDSL accepted an invalid character")
+	}
+}
+
+func TestBuildMidi_StrumDSL(t *testing.T) {
+	req := MidiRequest{
+		Chords:   []string{"C"},
+		Tempo:    120,
+		Pattern:  "strum-dsl",
+		Octave:   4,
+		Beats:    4,
+		StrumDSL: "D-DU-UDU",
+	}
+	// Group note-ons by tick: each strum sounds the whole triad at once.
+	strums := map[uint32][]byte{}
+	var tick uint32
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		tick += ev.delta
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+			strums[tick] = append(strums[tick], ev.data1)
+		}
+	}
+	eighth := uint32(ticksPerQuarter / 2)
+	down, up := []byte{60, 64, 67}, []byte{67, 64, 60}
+	want := map[uint32][]byte{
+		0: down, 2 * eighth: down, 3 * eighth: up,
+		5 * eighth: up, 6 * eighth: down, 7 * eighth: up,
+	}
+	if !reflect.DeepEqual(strums, want) {
+		t.Errorf("strums = %v, want %v", strums, want)
+	}
+}
+
+func TestBuildMidi_PerStringChannel(t *testing.T) {
+	req := MidiRequest{
+		Chords:           []string{"C"},
+		Tempo:            120,
+		Pattern:          "arpeggio-up",
+		Octave:           4,
+		Beats:            4,
+		OpenMidi:         []int{40, 45, 50, 55, 59, 64},
+		Frets:            [][]string{{"x", "3", "2", "0", "1", "0"}},
+		PerStringChannel: true,
+	}
+	// x32010: strings 1–5 sound C3 E3 G3 C4 E4.
+	want := map[byte]byte{48: 1, 52: 2, 55: 3, 60: 4, 64: 5}
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		kind := ev.status & 0xF0
+		if kind != 0x80 && kind != 0x90 {
+			continue
+		}
+		ch, ok := want[ev.data1]
+		if !ok {
+			continue // rest placeholder
+		}
+		if got := ev.status & 0x0F; got != ch {
+			t.Errorf("note %d on channel %d, want %d", ev.data1, got, ch)
+		}
+	}
+}
+
+func TestStringChannel_SkipsDrums(t *testing.T) {
+	if got := stringChannel(8); got != 8 {
+		t.Errorf("stringChannel(8) = %d, want 8", got)
+	}
+	if got := stringChannel(9); got != 10 {
+		t.Errorf("stringChannel(9) = %d, want 10 (channel 9 is drums)", got)
+	}
+}
+
+func TestBuildMidi_SwingOnlyStraightEighths(t *testing.T) {
+	render := func(pattern string, swing int) []midiEvent {
+		return trackEvents(t, buildMidi(MidiRequest{
+			Chords:  []string{"C"},
+			Tempo:   120,
+			Pattern: pattern,
+			Octave:  4,
+			Beats:   4,
+			Swing:   swing,
+		}))
+	}
+
+	// rock-8th: strums move from every 240 ticks to 0, 316, 480, 796, …
+	var strums []uint32
+	for _, tick := range onsets(render("rock-8th", 66)) {
+		if len(strums) == 0 || strums[len(strums)-1] != tick {
+			strums = append(strums, tick)
+		}
+	}
+	if len(strums) != 8 {
+		t.Fatalf("got %d strums, want 8", len(strums))
+	}
+	long := uint32(ticksPerQuarter * 66 / 100)
+	for i, tick := range strums {
+		want := uint32(i/2)*ticksPerQuarter + uint32(i%2)*long
+		if tick != want {
+			t.Errorf("rock-8th onset %d = %d, want %d", i, tick, want)
+		}
+	}
+	if sumDeltas(render("rock-8th", 66)) != 4*ticksPerQuarter {
+		t.Error("swing changed the length of the bar")
+	}
+
+	if !reflect.DeepEqual(render("bossa-nova", 66), render("bossa-nova", 0)) {
+		t.Error("swing changed bossa-nova, which defines its own feel")
+	}
+}
+
+func TestSwingWarnings(t *testing.T) {
+	req := MidiRequest{Pattern: "rock-8th", PatternSequence: []string{"bossa-nova", "pop-strum"}, Swing: 60}
+	got := swingWarnings(req)
+	if len(got) != 1 || !strings.Contains(got[0], "bossa-nova") {
+		t.Errorf("swingWarnings = %v, want one warning for bossa-nova", got)
+	}
+}
+
+func TestBuildMidi_StartTick(t *testing.T) {
+	req := MidiRequest{
+		Chords:           []string{"C", "G"},
+		Tempo:            120,
+		Pattern:          "quarter",
+		Octave:           4,
+		Beats:            4,
+		StartTick:        1920,
+		Drums:            true,
+		ClickSubdivision: "quarter",
+	}
+	for i, trk := range trackChunks(t, buildMidi(req)) {
+		got := onsets(decodeEvents(trk))
+		if len(got) == 0 || got[0] != req.StartTick {
+			t.Errorf("track %d first note-on at %v, want tick %d", i, got[:min(1, len(got))], req.StartTick)
+		}
+	}
+}
+
+func TestKeyNameToMidi(t *testing.T) {
+	cases := map[string]int{"C4": 60, "F#3": 54, "Bb2": 46, "A0": 21, "C": -1, "H4": -1, "G9": 127, "A9": -1}
+	for name, want := range cases {
+		if got := keyNameToMidi(name); got != want {
+			t.Errorf("keyNameToMidi(%q) = %d, want %d", name, got, want)
+		}
+	}
+}
+
+func TestBuildMidi_PianoKeys(t *testing.T) {
+	req := MidiRequest{
+		Chords:  []string{"C"},
+		Tempo:   120,
+		Pattern: "whole",
+		Octave:  2, // ignored: keys give the exact voicing
+		Beats:   4,
+		Keys:    [][]string{{"C4", "E4", "G4"}},
+	}
+	var notes []byte
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+			notes = append(notes, ev.data1)
+		}
+	}
+	if !bytes.Equal(notes, []byte{60, 64, 67}) {
+		t.Errorf("piano C notes = %v, want [60 64 67]", notes)
+	}
+}
+
+func TestBuildMidi_TieRepeats(t *testing.T) {
+	req := MidiRequest{
+		Chords:     []string{"C", "C", "G"},
+		Tempo:      120,
+		Pattern:    "whole",
+		Octave:     4,
+		Beats:      4,
+		TieRepeats: true,
+	}
+	bar := uint32(4 * ticksPerQuarter)
+	on := map[byte][]uint32{}  // note → note-on ticks
+	off := map[byte][]uint32{} // note → note-off ticks
+	var tick uint32
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		tick += ev.delta
+		switch {
+		case ev.data1 == 0 || ev.status == 0xFF:
+			// rest placeholder or meta event
+		case ev.status&0xF0 == 0x90 && ev.data2 > 0:
+			on[ev.data1] = append(on[ev.data1], tick)
+		case ev.status&0xF0 == 0x80:
+			off[ev.data1] = append(off[ev.data1], tick)
+		}
+	}
+	// C major (60, 64, 67) sounds once across both C bars; G's 67 is re-struck.
+	for _, n := range []byte{60, 64} {
+		if !reflect.DeepEqual(on[n], []uint32{0}) || !reflect.DeepEqual(off[n], []uint32{2 * bar}) {
+			t.Errorf("note %d: on %v off %v, want one note from 0 to %d", n, on[n], off[n], 2*bar)
+		}
+	}
+	if !reflect.DeepEqual(on[67], []uint32{0, 2 * bar}) {
+		t.Errorf("note 67 on at %v, want [0 %d]", on[67], 2*bar)
+	}
+	if sumDeltas(trackEvents(t, buildMidi(req))) != 3*bar {
+		t.Errorf("track length changed by tying")
+	}
+}
+
+func TestBalanceNotes_Restrike(t *testing.T) {
+	// 60 struck twice while held, then a single release; 64 never released.
+	var trk []byte
+	trk = append(trk, noteOnEvent(0, 0, 60, 100)...)
+	trk = append(trk, noteOnEvent(0, 0, 64, 100)...)
+	trk = append(trk, noteOnEvent(240, 0, 60, 100)...)
+	trk = append(trk, noteOffEvent(240, 0, 60, 0)...)
+	trk = append(trk, noteOffEvent(240, 0, 60, 0)...) // stray
+	got := decodeEvents(balanceNotes(trk, 0))
+
+	want := []midiEvent{
+		{0, 0x90, 60, 100},
+		{0, 0x90, 64, 100},
+		{240, 0x80, 60, 0}, // released before the re-strike
+		{0, 0x90, 60, 100},
+		{240, 0x80, 60, 0},
+		{240, 0x80, 64, 0}, // held note released at the end, stray off's delta kept
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("balanceNotes =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestBuildMidi_AllPatternsBalanced(t *testing.T) {
+	for pattern := range validPatterns {
+		t.Run(pattern, func(t *testing.T) {
+			req := MidiRequest{
+				Chords:   []string{"C", "Am7", "C"},
+				Tempo:    120,
+				Pattern:  pattern,
+				Octave:   3,
+				Beats:    4,
+				Rhythm:   []string{"q", "q", "h"},
+				StrumDSL: "D-DU-UDU",
+			}
+			held := map[[2]byte]bool{}
+			for _, ev := range trackEvents(t, buildMidi(req)) {
+				kind, key := ev.status&0xF0, [2]byte{ev.status & 0x0F, ev.data1}
+				if ev.data1 == 0 || (kind != 0x80 && kind != 0x90) {
+					continue
+				}
+				if kind == 0x90 && ev.data2 > 0 {
+					if held[key] {
+						t.Errorf("note %d struck again while held", ev.data1)
+					}
+					held[key] = true
+				} else {
+					if !held[key] {
+						t.Errorf("note-off for %d with no note-on", ev.data1)
+					}
+					held[key] = false
+				}
+			}
+			for key, on := range held {
+				if on {
+					t.Errorf("note %d still on at end of track", key[1])
+				}
+			}
+		})
+	}
+}
+
+// strumGaps returns, for each strum (note-ons starting within a sixteenth of
+// the first), the gap in ticks between its successive strings.
+func strumGaps(events []trackEvent) [][]uint32 {
+	var strums [][]uint32
+	var start, last uint32
+	for _, ev := range events {
+		if ev.status&0xF0 != 0x90 || ev.data2 == 0 {
+			continue
+		}
+		if len(strums) == 0 || ev.tick-start >= ticksPerQuarter/4 {
+			strums = append(strums, []uint32{})
+			start = ev.tick
+		} else {
+			strums[len(strums)-1] = append(strums[len(strums)-1], ev.tick-last)
+		}
+		last = ev.tick
+	}
+	return strums
+}
+
+func TestBuildMidi_UpStrumsSpreadLess(t *testing.T) {
+	req := MidiRequest{
+		Chords: []string{"C"}, Tempo: 120, Pattern: "pop-strum", Octave: 4, Beats: 4,
+		DownSpread: 20, UpSpread: 8,
+	}
+	strums := strumGaps(parseTrack(trackChunks(t, buildMidi(req))[0]))
+	// pop-strum: D D U D U
+	want := [][]uint32{{20, 20}, {20, 20}, {8, 8}, {20, 20}, {8, 8}}
+	if !reflect.DeepEqual(strums, want) {
+		t.Errorf("strum gaps = %v, want %v", strums, want)
+	}
+	if sumDeltas(trackEvents(t, buildMidi(req))) != 4*ticksPerQuarter {
+		t.Errorf("spread strums changed the bar length")
+	}
+}
+
+func TestBuildMidi_UpSpreadDefaultsToDown(t *testing.T) {
+	req := MidiRequest{
+		Chords: []string{"G"}, Tempo: 120, Pattern: "strum-dsl", Octave: 4, Beats: 4,
+		StrumDSL: "DUDU", DownSpread: 12,
+	}
+	for i, gaps := range strumGaps(parseTrack(trackChunks(t, buildMidi(req))[0])) {
+		if !reflect.DeepEqual(gaps, []uint32{12, 12}) {
+			t.Errorf("strum %d gaps = %v, want [12 12]", i, gaps)
+		}
+	}
+}
+
+func TestWalkingLine_ChordTones(t *testing.T) {
+	// Dm7 at octave 3 walks D2 F2 A2, then approaches G2 (43) from above.
+	line := walkingLine("Dm7", nil, chordToMidi("Dm7", 3), chordToMidi("G7", 3), 4)
+	if want := []byte{38, 41, 45, 44}; !bytes.Equal(line, want) {
+		t.Errorf("walkingLine = %v, want %v", line, want)
+	}
+}
+
+func TestWalkingLine_CustomIntervals(t *testing.T) {
+	// A custom minor-triad quality walks C2 Eb2 G2, not the major fallback's E2.
+	custom := map[string][]int{"x": {7, 0, 3}}
+	line := walkingLine("Cx", custom, voiceChord("Cx", 3, custom), chordToMidi("G7", 3), 4)
+	if want := []byte{36, 39, 43, 42}; !bytes.Equal(line, want) {
+		t.Errorf("walkingLine = %v, want %v", line, want)
+	}
+}
+
+func TestBuildMidi_WalkingBassTwoFiveOne(t *testing.T) {
+	req := MidiRequest{
+		Chords:  []string{"Dm7", "G7", "Cmaj7"},
+		Tempo:   120,
+		Pattern: "walking-bass",
+		Octave:  3,
+		Beats:   4,
+	}
+	var line []byte
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+			line = append(line, ev.data1)
+		}
+	}
+	if len(line) != 12 {
+		t.Fatalf("got %d bass notes, want 12", len(line))
+	}
+	roots := []byte{38, 43, 36} // D2, G2, C2
+	for bar, root := range roots {
+		if line[bar*4] != root {
+			t.Errorf("bar %d beat 1 = %d, want root %d", bar, line[bar*4], root)
+		}
+		next := roots[(bar+1)%len(roots)]
+		if d := int(line[bar*4+3]) - int(next); d != 1 && d != -1 {
+			t.Errorf("bar %d beat 4 = %d, want a half step from %d", bar, line[bar*4+3], next)
+		}
+	}
+}
+
+func TestBuildMidi_ScaleRun(t *testing.T) {
+	req := MidiRequest{
+		Chords:  []string{"C", "Am"},
+		Tempo:   120,
+		Pattern: "scale-run",
+		Octave:  4,
+		Beats:   4,
+	}
+	var run []byte
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+			run = append(run, ev.data1)
+		}
+	}
+	if len(run) != 32 {
+		t.Fatalf("got %d notes, want 16 sixteenths per bar", len(run))
+	}
+	// C major from C4, A natural minor from A4 (the chord's root).
+	wantStarts := map[int][]byte{0: {60, 62, 64, 65, 67, 69, 71, 72}, 16: {69, 71, 72, 74, 76, 77, 79, 81}}
+	for start, want := range wantStarts {
+		if got := run[start : start+len(want)]; !bytes.Equal(got, want) {
+			t.Errorf("run from note %d = %v, want %v", start, got, want)
+		}
+	}
+	for bar := 0; bar < 2; bar++ {
+		for i := bar*16 + 1; i < bar*16+16; i++ {
+			if step := int(run[i]) - int(run[i-1]); step != 1 && step != 2 {
+				t.Errorf("bar %d: %d → %d is not a scale step", bar, run[i-1], run[i])
+			}
+		}
+	}
+}
+
+func TestBuildMidi_ScaleRunFollowsTransposition(t *testing.T) {
+	req := MidiRequest{
+		Chords:          []string{"C"},
+		Tempo:           120,
+		Pattern:         "scale-run",
+		Octave:          4,
+		Beats:           4,
+		Repeat:          2,
+		RepeatTranspose: 2,
+	}
+	var run []byte
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+			run = append(run, ev.data1)
+		}
+	}
+	if len(run) != 32 {
+		t.Fatalf("got %d notes, want 32", len(run))
+	}
+	// The second pass sounds D, so it runs D major: D E F# G.
+	if got, want := run[16:20], []byte{62, 64, 66, 67}; !bytes.Equal(got, want) {
+		t.Errorf("second pass starts %v, want D major %v", got, want)
+	}
+
+	// MaxRootMidi pulls C down to A, which should then run A major.
+	req.Repeat, req.RepeatTranspose, req.rootShift = 1, 0, -3
+	run = run[:0]
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+			run = append(run, ev.data1)
+		}
+	}
+	if got, want := run[:4], []byte{57, 59, 61, 62}; !bytes.Equal(got, want) {
+		t.Errorf("rootShift -3 starts %v, want A major %v", got, want)
+	}
+}
+
+func TestScaleRun_CustomIntervals(t *testing.T) {
+	cases := []struct {
+		chord  string
+		custom map[string][]int
+		want   []byte
+	}{
+		{"Cx", map[string][]int{"x": {0, 3, 7}}, []byte{60, 62, 63, 65}}, // new minor quality: C minor
+		{"Cm", map[string][]int{"m": {0, 4, 7}}, []byte{60, 62, 64, 65}}, // "m" redefined as major
+	}
+	for _, tc := range cases {
+		if got := scaleRun(tc.chord, tc.custom, voiceChord(tc.chord, 4, tc.custom), 4); !bytes.Equal(got, tc.want) {
+			t.Errorf("scaleRun(%s, %v) = %v, want %v", tc.chord, tc.custom, got, tc.want)
+		}
+	}
+}
+
+func TestBuildMidi_Bossa2BarAlternates(t *testing.T) {
+	req := MidiRequest{
+		Chords:  []string{"C", "C", "G", "G"},
+		Tempo:   120,
+		Pattern: "bossa-2bar",
+		Octave:  4,
+		Beats:   4,
+	}
+	bar := uint32(4 * ticksPerQuarter)
+	// comp[b] lists the eighths of bar b where the chord's third is struck.
+	comp := make([][]uint32, 4)
+	var now uint32
+	for _, ev := range trackEvents(t, buildMidi(req)) {
+		now += ev.delta
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 && (ev.data1 == 64 || ev.data1 == 71) {
+			b := now / bar
+			comp[b] = append(comp[b], (now%bar)/(ticksPerQuarter/2))
+		}
+	}
+	even, odd := []uint32{0, 3, 6}, []uint32{2, 5}
+	for b, want := range [][]uint32{even, odd, even, odd} {
+		if !reflect.DeepEqual(comp[b], want) {
+			t.Errorf("bar %d comps on eighths %v, want %v", b, comp[b], want)
+		}
+	}
+	if got, want := sumDeltas(trackEvents(t, buildMidi(req))), 4*bar; got != want {
+		t.Errorf("length = %d ticks, want %d", got, want)
+	}
+}
+
+func TestBuildMidi_Feel(t *testing.T) {
+	onsPerBar := func(feel string) (int, uint32) {
+		req := MidiRequest{Chords: []string{"C", "G"}, Tempo: 120, Pattern: "rock-8th", Octave: 4, Beats: 4, Feel: feel}
+		events := trackEvents(t, buildMidi(req))
+		ons := 0
+		for _, ev := range events {
+			if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+				ons++
+			}
+		}
+		return ons / 2, sumDeltas(events)
+	}
+	normal, length := onsPerBar("normal")
+	double, doubleLength := onsPerBar("double")
+	half, halfLength := onsPerBar("half")
+	if double != 2*normal {
+		t.Errorf("double time has %d note-ons per bar, want %d", double, 2*normal)
+	}
+	if half != normal/2 {
+		t.Errorf("half time has %d note-ons per bar, want %d", half, normal/2)
+	}
+	if doubleLength != length || halfLength != length {
+		t.Errorf("lengths normal/double/half = %d/%d/%d ticks, want all equal", length, doubleLength, halfLength)
+	}
+}
+
+// ── validPatterns map ─────────────────────────────────────────────────────
+
+func TestValidPatterns_Count(t *testing.T) {
+	// 31 UI selector patterns plus the API-only "walking-bass", "bossa-2bar",
+	// "scale-run", "arpeggio", "custom" and "strum-dsl"
+	if len(validPatterns) != 37 {
+		t.Errorf("validPatterns has %d entries, want 37", len(validPatterns))
+	}
+}
+
+func TestBuildMidi_CustomIntervals(t *testing.T) {
+	cases := []struct {
+		chord  string
+		custom map[string][]int
+		want   []byte
+	}{
+		{"Coct", map[string][]int{"oct": {0, 12}}, []byte{60, 72}},
+		{"C5", nil, []byte{60, 67}},
+		{"Cquartal", nil, []byte{60, 65, 70}},
+		{"C5", map[string][]int{"5": {0, 7, 12}}, []byte{60, 67, 72}},    // overrides the table
+		{"Cx", map[string][]int{"x": {12, 0, 7, 0}}, []byte{60, 67, 72}}, // sorted, bass first, no doubled note
+	}
+	for _, tc := range cases {
+		req := MidiRequest{
+			Chords:          []string{tc.chord},
+			Tempo:           120,
+			Pattern:         "whole",
+			Octave:          4,
+			Beats:           4,
+			CustomIntervals: tc.custom,
+		}
+		var notes []byte
+		for _, ev := range trackEvents(t, buildMidi(req)) {
+			if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+				notes = append(notes, ev.data1)
+			}
+		}
+		if !bytes.Equal(notes, tc.want) {
+			t.Errorf("%s with %v = %v, want %v", tc.chord, tc.custom, notes, tc.want)
+		}
+	}
+}
+
+func TestBuildMidi_DropVoicings(t *testing.T) {
+	cases := map[string][]byte{
+		"close": {60, 64, 67, 71}, // C4 E4 G4 B4
+		"drop2": {55, 60, 64, 71}, // G drops to G3
+		"drop3": {52, 60, 67, 71}, // E drops to E3
+	}
+	for voicing, want := range cases {
+		req := MidiRequest{
+			Chords:  []string{"Cmaj7"},
+			Tempo:   120,
+			Pattern: "whole",
+			Octave:  4,
+			Beats:   4,
+			Voicing: voicing,
+		}
+		var notes []byte
+		for _, ev := range trackEvents(t, buildMidi(req)) {
+			if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+				notes = append(notes, ev.data1)
+			}
+		}
+		if !bytes.Equal(notes, want) {
+			t.Errorf("%s Cmaj7 = %v, want %v", voicing, notes, want)
+		}
+	}
+	if got := dropVoicing([]byte{60, 64, 67}, 2); !bytes.Equal(got, []byte{60, 64, 67}) {
+		t.Errorf("drop2 triad = %v, want it unchanged", got)
 	}
 }
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SongSection is one part of a song (verse, chorus, …) for POST /api/song.
+type SongSection struct {
+	Name    string   `json:"name"`    // optional label, e.g. "verse"
+	Chords  []string `json:"chords"`  // chords for one pass of the section
+	Pattern string   `json:"pattern"` // default "quarter"; "custom" and "strum-dsl" are not supported
+	Repeat  int      `json:"repeat"`  // passes through the chords, 1–16 (default 1)
+	Tempo   int      `json:"tempo"`   // BPM for this section; 0 = the song tempo
+
+	LeadInChord string `json:"leadInChord"` // optional pickup chord played on the last beat before the section's downbeat
+}
+
+// SongRequest is the JSON body for POST /api/song.
+type SongRequest struct {
+	Sections []SongSection `json:"sections" binding:"required"`
+	Tempo    int           `json:"tempo"`  // default BPM (default 120)
+	Octave   int           `json:"octave"` // base octave (default 4)
+	Beats    int           `json:"beats"`  // beats per chord (default 4)
+}
+
+// buildSong renders each section with buildTrack and stitches the event
+// streams into one track. Every section opens with its own tempo event at
+// delta 0 straight after the previous section's last note-off, so timing is
+// continuous across the joins. A section's LeadInChord is a one-beat pickup
+// on the last beat before its downbeat: it takes that beat from the end of
+// the previous section, so every section still starts on a bar line, and
+// before the first section it is a one-beat anacrusis.
+func buildSong(req SongRequest) []byte {
+	eot := endOfTrack()
+	// body strips a rendered track down to its note events.
+	body := func(trk []byte, tempo int) []byte {
+		return bytes.TrimSuffix(bytes.TrimPrefix(trk, tempoEvent(tempo)), eot)
+	}
+	var trk []byte
+	var prev []byte // the previous section's note events, held back for a pickup
+	for _, sec := range req.Sections {
+		tempo := sec.Tempo
+		if tempo == 0 {
+			tempo = req.Tempo
+		}
+		section := body(buildTrack(MidiRequest{
+			Chords:  sec.Chords,
+			Tempo:   tempo,
+			Pattern: sec.Pattern,
+			Octave:  req.Octave,
+			Beats:   req.Beats,
+			Repeat:  sec.Repeat,
+		}), tempo)
+		var pickup []byte
+		if sec.LeadInChord != "" {
+			pickup = body(buildTrack(MidiRequest{
+				Chords:  []string{sec.LeadInChord},
+				Tempo:   tempo,
+				Pattern: "whole",
+				Octave:  req.Octave,
+				Beats:   1,
+			}), tempo)
+			if events := parseTrack(prev); len(events) > 0 {
+				prev = truncateSlot(prev, events[len(events)-1].tick-ticksPerQuarter, 0)
+			}
+		}
+		// The section's tempo starts with its pickup.
+		trk = append(trk, prev...)
+		trk = append(trk, tempoEvent(tempo)...)
+		trk = append(trk, pickup...)
+		prev = section
+	}
+	trk = append(trk, prev...)
+	trk = append(trk, eot...)
+	return writeSMF([][]byte{trk})
+}
+
+// GenerateSong handles POST /api/song, concatenating sections into one MIDI file.
+func GenerateSong(c *gin.Context) {
+	var req SongRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Sections) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sections must not be empty"})
+		return
+	}
+
+	// Apply defaults
+	if req.Tempo <= 0 || req.Tempo > 300 {
+		req.Tempo = 120
+	}
+	if req.Octave < 0 || req.Octave > 8 {
+		req.Octave = 4
+	}
+	if req.Beats <= 0 {
+		req.Beats = 4
+	}
+
+	for i := range req.Sections {
+		sec := &req.Sections[i]
+		if len(sec.Chords) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("sections[%d]: chords must not be empty", i)})
+			return
+		}
+		if sec.Pattern == "" {
+			sec.Pattern = "quarter"
+		}
+		if !validPatterns[sec.Pattern] || sec.Pattern == "custom" || sec.Pattern == "strum-dsl" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("sections[%d]: unknown pattern: %s", i, sec.Pattern)})
+			return
+		}
+		if sec.Repeat < 0 || sec.Repeat > 16 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("sections[%d]: repeat must be in range 1–16", i)})
+			return
+		}
+		if sec.LeadInChord != "" && chordRootIndex(sec.LeadInChord) == -1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("sections[%d]: invalid leadInChord: %s", i, sec.LeadInChord)})
+			return
+		}
+		if sec.Tempo < 0 || sec.Tempo > 300 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("sections[%d]: tempo must be in range 1–300", i)})
+			return
+		}
+	}
+
+	midi := buildSong(req)
+
+	requestLogger(c).Info("song generated",
+		"sections", len(req.Sections),
+		"bytes", len(midi),
+	)
+
+	c.Header("Content-Disposition", "attachment; filename=\"song.mid\"")
+	c.Data(http.StatusOK, "audio/midi", midi)
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBuildSong_ContinuousTiming(t *testing.T) {
+	req := SongRequest{
+		Sections: []SongSection{
+			{Name: "verse", Chords: []string{"C", "G"}, Pattern: "quarter", Repeat: 2},
+			{Name: "chorus", Chords: []string{"F", "C", "G"}, Pattern: "arpeggio-up", Tempo: 140},
+		},
+		Tempo:  100,
+		Octave: 4,
+		Beats:  4,
+	}
+	midi := buildSong(req)
+	chunks := trackChunks(t, midi)
+	if len(chunks) != 1 {
+		t.Fatalf("got %d tracks, want 1", len(chunks))
+	}
+	events := decodeEvents(chunks[0])
+
+	// 2 chords × 2 passes + 3 chords = 7 bars
+	if got, want := sumDeltas(events), uint32(7*4*ticksPerQuarter); got != want {
+		t.Errorf("song length = %d ticks, want %d", got, want)
+	}
+	var tempos, eots int
+	for _, ev := range events {
+		if ev.status == 0xFF && ev.data1 == 0x51 {
+			tempos++
+		}
+		if ev.status == 0xFF && ev.data1 == 0x2F {
+			eots++
+		}
+	}
+	if tempos != 2 {
+		t.Errorf("got %d tempo events, want one per section (2)", tempos)
+	}
+	if eots != 1 {
+		t.Errorf("got %d end-of-track events, want 1", eots)
+	}
+}
+
+func TestGenerateSong_Endpoint(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"sections": []map[string]interface{}{
+			{"chords": []string{"Am", "F"}, "pattern": "whole"},
+			{"chords": []string{"C", "G"}, "pattern": "pop-strum"},
+		},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/song", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/song = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	if midi := w.Body.Bytes(); len(midi) < 4 || string(midi[0:4]) != "MThd" {
+		t.Errorf("response is not a valid MIDI file")
+	}
+}
+
+func TestGenerateSong_EmptySection(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"sections": []map[string]interface{}{
+			{"chords": []string{"C"}},
+			{"chords": []string{}},
+		},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/song", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("empty section = %d, want 400", w.Code)
+	}
+}
+
+func TestBuildSong_LeadInChord(t *testing.T) {
+	req := SongRequest{
+		Sections: []SongSection{
+			{Name: "chorus", Chords: []string{"C"}, Pattern: "whole"},
+			{Name: "bridge", Chords: []string{"F"}, Pattern: "whole", LeadInChord: "D"},
+		},
+		Tempo:  100,
+		Octave: 4,
+		Beats:  4,
+	}
+	events := decodeEvents(trackChunks(t, buildSong(req))[0])
+
+	if got, want := sumDeltas(events), uint32(8*ticksPerQuarter); got != want {
+		t.Errorf("song length = %d ticks, want %d (the pickup takes the chorus's last beat)", got, want)
+	}
+	// The D chord's F# (66) sounds on beat 4 of the chorus bar, cutting its
+	// C (60) short, and the bridge's F (65) lands on the next bar line.
+	var now uint32
+	onsets, releases := map[byte]uint32{}, map[byte]uint32{}
+	var tempos int
+	for _, ev := range events {
+		now += ev.delta
+		if ev.status == 0xFF && ev.data1 == 0x51 {
+			tempos++
+		}
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+			if _, seen := onsets[ev.data1]; !seen {
+				onsets[ev.data1] = now
+			}
+		} else if ev.status&0xF0 == 0x80 || ev.status&0xF0 == 0x90 {
+			if _, seen := releases[ev.data1]; !seen {
+				releases[ev.data1] = now
+			}
+		}
+	}
+	bridge := uint32(4 * ticksPerQuarter)
+	if fs, ok := onsets[66]; !ok || fs != bridge-ticksPerQuarter {
+		t.Errorf("lead-in F# onset = %d (present %v), want %d", fs, ok, bridge-ticksPerQuarter)
+	}
+	if c := releases[60]; c != bridge-ticksPerQuarter {
+		t.Errorf("chorus C released at %d, want %d", c, bridge-ticksPerQuarter)
+	}
+	if f := onsets[65]; f != bridge {
+		t.Errorf("bridge F onset = %d, want %d", f, bridge)
+	}
+	if tempos != 2 {
+		t.Errorf("got %d tempo events, want one per section (2)", tempos)
+	}
+}
+
+func TestBuildSong_LeadInChordFirstSection(t *testing.T) {
+	req := SongRequest{
+		Sections: []SongSection{
+			{Chords: []string{"C"}, Pattern: "whole", LeadInChord: "G"},
+		},
+		Tempo:  100,
+		Octave: 4,
+		Beats:  4,
+	}
+	events := decodeEvents(trackChunks(t, buildSong(req))[0])
+
+	// A one-beat anacrusis, then the full first bar.
+	if got, want := sumDeltas(events), uint32(5*ticksPerQuarter); got != want {
+		t.Errorf("song length = %d ticks, want %d", got, want)
+	}
+	var now uint32
+	onsets := map[byte]uint32{}
+	for _, ev := range events {
+		now += ev.delta
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 {
+			if _, seen := onsets[ev.data1]; !seen {
+				onsets[ev.data1] = now
+			}
+		}
+	}
+	if g, ok := onsets[67]; !ok || g != 0 {
+		t.Errorf("pickup G onset = %d (present %v), want 0", g, ok)
+	}
+	if c := onsets[60]; c != ticksPerQuarter {
+		t.Errorf("first C onset = %d, want %d", c, ticksPerQuarter)
+	}
+}
@@ -0,0 +1,728 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"guitartutor/backend/models"
+)
+
+var chromaticFlats = []string{"C", "Db", "D", "Eb", "E", "F", "Gb", "G", "Ab", "A", "Bb", "B"}
+
+// flatMajorKeys holds the semitone indices of major keys conventionally spelled with flats.
+var flatMajorKeys = map[int]bool{5: true, 10: true, 3: true, 8: true, 1: true, 6: true}
+
+// keyUsesFlats reports whether chords in key should be spelled with flats.
+// An explicit sharp in the key name ("C#", "F#m") always wins; minor keys
+// follow their relative major.
+func keyUsesFlats(key string) bool {
+	idx := chordRootIndex(key)
+	if idx == -1 {
+		return false
+	}
+	if len(key) > 1 && key[1] == '#' {
+		return false
+	}
+	if len(key) > 1 && key[1] == 'b' {
+		return true
+	}
+	if chordSuffix(key) == "m" {
+		idx = (idx + 3) % 12
+	}
+	return flatMajorKeys[idx]
+}
+
+// spellChord builds a chord name from a root index and suffix using sharp or flat spelling.
+func spellChord(rootIdx int, suffix string, flats bool) string {
+	rootIdx = ((rootIdx % 12) + 12) % 12
+	if flats {
+		return chromaticFlats[rootIdx] + suffix
+	}
+	return chromatic[rootIdx] + suffix
+}
+
+// substitutionsFor returns the common reharmonisation options for chord in key.
+func substitutionsFor(chord, key string) []models.Substitution {
+	root := chordRootIndex(chord)
+	suffix := chordSuffix(chord)
+	flats := keyUsesFlats(key)
+	subs := []models.Substitution{}
+
+	switch suffix {
+	case "", "maj7":
+		minor := "m"
+		if suffix == "maj7" {
+			minor = "m7"
+		}
+		subs = append(subs, models.Substitution{
+			Type:        "relative-minor",
+			Chords:      []string{spellChord(root+9, minor, flats)},
+			Description: "Relative minor shares two of the three triad tones",
+		})
+	case "m", "m7":
+		major := ""
+		if suffix == "m7" {
+			major = "maj7"
+		}
+		subs = append(subs, models.Substitution{
+			Type:        "relative-major",
+			Chords:      []string{spellChord(root+3, major, flats)},
+			Description: "Relative major shares two of the three triad tones",
+		})
+	case "7":
+		// Tritone subs are conventionally written as bII7, so always spell with flats.
+		subs = append(subs, models.Substitution{
+			Type:        "tritone",
+			Chords:      []string{spellChord(root+6, "7", true)},
+			Description: "Dominant a tritone away shares the same 3rd and 7th",
+		})
+	}
+
+	// ii-V insertion: approach the chord's dominant function with its related ii.
+	// For a dominant that is the chord itself; otherwise the V7 of the target.
+	dominant := root
+	if suffix != "7" {
+		dominant = root + 7
+	}
+	subs = append(subs, models.Substitution{
+		Type:        "ii-V",
+		Chords:      []string{spellChord(dominant+7, "m7", flats), spellChord(dominant, "7", flats)},
+		Description: "Insert the related ii-V leading into the chord",
+	})
+	return subs
+}
+
+// diatonicChord is one triad built on a scale degree of a key.
+type diatonicChord struct {
+	root    int    // semitone index 0–11
+	suffix  string // "", "m" or "dim"
+	numeral string // Roman-numeral function, e.g. "IV" or "vii°"
+	seventh string // diatonic seventh-chord suffix, e.g. "maj7" or "m7b5"
+}
+
+var (
+	majorScale     = []int{0, 2, 4, 5, 7, 9, 11}
+	majorQualities = []string{"", "m", "m", "", "", "m", "dim"}
+	majorNumerals  = []string{"I", "ii", "iii", "IV", "V", "vi", "vii°"}
+	majorSevenths  = []string{"maj7", "m7", "m7", "maj7", "7", "m7", "m7b5"}
+
+	minorScale     = []int{0, 2, 3, 5, 7, 8, 10}
+	minorQualities = []string{"m", "dim", "", "m", "m", "", ""}
+	minorNumerals  = []string{"i", "ii°", "III", "iv", "v", "VI", "VII"}
+	minorSevenths  = []string{"m7", "m7b5", "maj7", "m7", "m7", "maj7", "7"}
+)
+
+// diatonicChords returns the seven diatonic triads of key ("C", "F#", "Am").
+// Minor keys use the natural minor scale. Returns nil for an unrecognised key.
+func diatonicChords(key string) []diatonicChord {
+	tonic := chordRootIndex(key)
+	if tonic == -1 {
+		return nil
+	}
+	scale, qualities, numerals, sevenths := majorScale, majorQualities, majorNumerals, majorSevenths
+	if chordSuffix(key) == "m" {
+		scale, qualities, numerals, sevenths = minorScale, minorQualities, minorNumerals, minorSevenths
+	}
+	chords := make([]diatonicChord, len(scale))
+	for i, iv := range scale {
+		chords[i] = diatonicChord{
+			root:    (tonic + iv) % 12,
+			suffix:  qualities[i],
+			numeral: numerals[i],
+			seventh: sevenths[i],
+		}
+	}
+	return chords
+}
+
+// pivotChords returns the triads diatonic to both keys, in scale order of from.
+func pivotChords(from, to string) []models.PivotChord {
+	toChords := diatonicChords(to)
+	flats := keyUsesFlats(from)
+	pivots := []models.PivotChord{}
+	for _, fc := range diatonicChords(from) {
+		for _, tc := range toChords {
+			if fc.root == tc.root && fc.suffix == tc.suffix {
+				pivots = append(pivots, models.PivotChord{
+					Chord: spellChord(fc.root, fc.suffix, flats),
+					From:  fc.numeral,
+					To:    tc.numeral,
+				})
+			}
+		}
+	}
+	return pivots
+}
+
+// GetPivotChords handles GET /api/pivot?from=C&to=G, listing the chords shared
+// by both keys along with their function in each.
+func GetPivotChords(c *gin.Context) {
+	from, to := c.Query("from"), c.Query("to")
+	if chordRootIndex(from) == -1 || chordRootIndex(to) == -1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be valid keys"})
+		return
+	}
+	c.JSON(http.StatusOK, models.PivotResponse{
+		From:   from,
+		To:     to,
+		Pivots: pivotChords(from, to),
+	})
+}
+
+// Substitute suggests common chord substitutions for a chord in a key.
+func Substitute(c *gin.Context) {
+	var req models.SubstituteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if chordRootIndex(req.Chord) == -1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unrecognised chord: " + req.Chord})
+		return
+	}
+	if chordRootIndex(req.Key) == -1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unrecognised key: " + req.Key})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SubstituteResponse{
+		Chord:         req.Chord,
+		Key:           req.Key,
+		Substitutions: substitutionsFor(req.Chord, req.Key),
+	})
+}
+
+// simplifyChord reduces a chord name to a beginner-friendly triad: extensions,
+// alterations, suspensions and slash basses are dropped, keeping the root and
+// its major/minor quality. Diminished and augmented chords keep their triad.
+func simplifyChord(chord string) string {
+	root := chordRootIndex(chord)
+	if root == -1 {
+		return chord
+	}
+	suffix := chordSuffix(chord)
+	if i := strings.Index(suffix, "/"); i != -1 {
+		suffix = suffix[:i]
+	}
+	quality := ""
+	switch {
+	case strings.HasPrefix(suffix, "dim"), strings.HasPrefix(suffix, "°"), strings.HasPrefix(suffix, "m7b5"):
+		quality = "dim"
+	case strings.HasPrefix(suffix, "aug"), strings.HasPrefix(suffix, "+"):
+		quality = "aug"
+	case strings.HasPrefix(suffix, "m") && !strings.HasPrefix(suffix, "maj"):
+		quality = "m"
+	}
+	// Keep the caller's root spelling ("Bb" stays "Bb").
+	return chord[:len(chord)-len(chordSuffix(chord))] + quality
+}
+
+// Simplify reduces each chord in the request to its basic triad.
+func Simplify(c *gin.Context) {
+	var req models.SimplifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	results := make([]models.SimplifiedChord, len(req.Chords))
+	for i, ch := range req.Chords {
+		results[i] = models.SimplifiedChord{
+			Original:   ch,
+			Simplified: simplifyChord(ch),
+		}
+	}
+	c.JSON(http.StatusOK, models.SimplifyResponse{Results: results})
+}
+
+// addSeventh upgrades a diatonic triad in key to its diatonic seventh chord
+// ("F" in C → "Fmaj7", "G" → "G7"). Anything that isn't a plain diatonic
+// triad, including chords that already carry a seventh, is returned unchanged.
+func addSeventh(chord, key string) string {
+	root := chordRootIndex(chord)
+	suffix := chordSuffix(chord)
+	for _, dc := range diatonicChords(key) {
+		if dc.root == root && dc.suffix == suffix {
+			// Keep the caller's root spelling ("Bb" stays "Bb").
+			return chord[:len(chord)-len(suffix)] + dc.seventh
+		}
+	}
+	return chord
+}
+
+// AddSevenths handles POST /api/add-sevenths, upgrading each diatonic triad
+// in a progression to its seventh chord in the given key.
+func AddSevenths(c *gin.Context) {
+	var req models.AddSeventhsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if chordRootIndex(req.Key) == -1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unrecognised key: " + req.Key})
+		return
+	}
+	results := make([]models.ReharmonizedChord, len(req.Chords))
+	for i, ch := range req.Chords {
+		results[i] = models.ReharmonizedChord{
+			Original:     ch,
+			Reharmonized: addSeventh(ch, req.Key),
+		}
+	}
+	c.JSON(http.StatusOK, models.AddSeventhsResponse{Key: req.Key, Results: results})
+}
+
+// parallelKey returns the major/minor key on the same tonic ("C" ↔ "Cm").
+func parallelKey(key string) string {
+	root := key[:len(key)-len(chordSuffix(key))]
+	if chordSuffix(key) == "m" {
+		return root
+	}
+	return root + "m"
+}
+
+// toParallel moves chord from key to the same scale degree of the parallel key.
+// Minor keys are natural minor, so C major's V (G) becomes v (Gm), not the
+// harmonic-minor G. Diatonic sevenths stay sevenths (G7 → Gm7); chords that
+// aren't a diatonic triad or seventh pass through unchanged.
+func toParallel(chord, key string) string {
+	root := chordRootIndex(chord)
+	suffix := chordSuffix(chord)
+	target := parallelKey(key)
+	parallel := diatonicChords(target)
+	for i, dc := range diatonicChords(key) {
+		if dc.root != root {
+			continue
+		}
+		switch suffix {
+		case dc.suffix:
+			return spellChord(parallel[i].root, parallel[i].suffix, keyUsesFlats(target))
+		case dc.seventh:
+			return spellChord(parallel[i].root, parallel[i].seventh, keyUsesFlats(target))
+		}
+	}
+	return chord
+}
+
+// Parallel handles POST /api/parallel, rewriting a progression in the
+// parallel major or minor key by scale degree.
+func Parallel(c *gin.Context) {
+	var req models.ParallelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if chordRootIndex(req.Key) == -1 || (chordSuffix(req.Key) != "" && chordSuffix(req.Key) != "m") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unrecognised key: " + req.Key})
+		return
+	}
+	results := make([]models.ReharmonizedChord, len(req.Chords))
+	for i, ch := range req.Chords {
+		results[i] = models.ReharmonizedChord{
+			Original:     ch,
+			Reharmonized: toParallel(ch, req.Key),
+		}
+	}
+	c.JSON(http.StatusOK, models.ParallelResponse{
+		Key:         req.Key,
+		ParallelKey: parallelKey(req.Key),
+		Results:     results,
+	})
+}
+
+// modes are the seven modes of the major scale, in scale-degree order.
+var modes = []struct {
+	name      string
+	intervals []int
+}{
+	{"Ionian", []int{0, 2, 4, 5, 7, 9, 11}},
+	{"Dorian", []int{0, 2, 3, 5, 7, 9, 10}},
+	{"Phrygian", []int{0, 1, 3, 5, 7, 8, 10}},
+	{"Lydian", []int{0, 2, 4, 6, 7, 9, 11}},
+	{"Mixolydian", []int{0, 2, 4, 5, 7, 9, 10}},
+	{"Aeolian", []int{0, 2, 3, 5, 7, 8, 10}},
+	{"Locrian", []int{0, 1, 3, 5, 6, 8, 10}},
+}
+
+// qualityModes picks a mode from a chord's quality alone, for chords that
+// aren't diatonic to the key.
+var qualityModes = map[string]int{"": 0, "maj7": 0, "m": 1, "m7": 1, "7": 4, "dim": 6, "m7b5": 6}
+
+// soloModeFor returns the index into modes that fits chord in key, and whether
+// the choice came from the chord's place in the key. A diatonic triad plays
+// the mode of its scale degree unless the chord's seventh contradicts the key
+// (C7 in C); everything else falls back to qualityModes.
+func soloModeFor(chord, key string) (int, bool) {
+	root := chordRootIndex(chord)
+	suffix := chordSuffix(chord)
+	triad := chordSuffix(simplifyChord(chord))
+	// Minor keys number their degrees from the relative major's sixth.
+	offset := 0
+	if chordSuffix(key) == "m" {
+		offset = 5
+	}
+	for i, dc := range diatonicChords(key) {
+		if dc.root != root || dc.suffix != triad {
+			continue
+		}
+		if strings.Contains(suffix, "7") && suffix != dc.seventh {
+			break
+		}
+		return (i + offset) % len(modes), true
+	}
+	if m, ok := qualityModes[suffix]; ok {
+		return m, false
+	}
+	return qualityModes[triad], false
+}
+
+// soloGuideFor builds the soloing advice for one chord in key.
+func soloGuideFor(chord, key string) models.SoloGuideChord {
+	root := chordRootIndex(chord)
+	flats := keyUsesFlats(key)
+	mode, diatonic := soloModeFor(chord, key)
+
+	intervals, ok := qualityIntervals[chordSuffix(chord)]
+	if !ok {
+		intervals = qualityIntervals[chordSuffix(simplifyChord(chord))]
+	}
+	isChordTone := map[int]bool{}
+	guide := models.SoloGuideChord{
+		Chord:        chord,
+		Scale:        spellChord(root, "", flats) + " " + modes[mode].name,
+		Diatonic:     diatonic,
+		ChordTones:   []string{},
+		PassingTones: []string{},
+	}
+	for _, iv := range intervals {
+		isChordTone[iv%12] = true
+		guide.ChordTones = append(guide.ChordTones, spellChord(root+iv, "", flats))
+	}
+	for _, iv := range modes[mode].intervals {
+		name := spellChord(root+iv, "", flats)
+		guide.ScaleNotes = append(guide.ScaleNotes, name)
+		if !isChordTone[iv] {
+			guide.PassingTones = append(guide.PassingTones, name)
+		}
+	}
+	return guide
+}
+
+// SoloGuide handles POST /api/solo-guide, suggesting a scale for each chord of
+// a progression and splitting it into chord tones and passing tones.
+// ?notation=solfege names the notes Do, Re, Mi… instead of C, D, E….
+func SoloGuide(c *gin.Context) {
+	notation, ok := noteNotation(c)
+	if !ok {
+		return
+	}
+	var req models.SoloGuideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if chordRootIndex(req.Key) == -1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unrecognised key: " + req.Key})
+		return
+	}
+	guides := make([]models.SoloGuideChord, len(req.Chords))
+	for i, ch := range req.Chords {
+		if chordRootIndex(ch) == -1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unrecognised chord: " + ch})
+			return
+		}
+		g := soloGuideFor(ch, req.Key)
+		g.Scale = labelNote(g.Scale, notation)
+		labelNotes(g.ScaleNotes, notation)
+		labelNotes(g.ChordTones, notation)
+		labelNotes(g.PassingTones, notation)
+		guides[i] = g
+	}
+	c.JSON(http.StatusOK, models.SoloGuideResponse{Key: req.Key, Chords: guides})
+}
+
+// tritoneSub returns the tritone substitution for chord in key when it is a
+// dominant seventh, and chord unchanged otherwise.
+func tritoneSub(chord, key string) string {
+	for _, sub := range substitutionsFor(chord, key) {
+		if sub.Type == "tritone" {
+			return sub.Chords[0]
+		}
+	}
+	return chord
+}
+
+// Reharmonize handles POST /api/reharmonize?style=tritone, replacing every
+// dominant seventh with its tritone substitution and returning a jazz-swing
+// MIDI preview of the result. "tritone" is currently the only style.
+func Reharmonize(c *gin.Context) {
+	style := c.DefaultQuery("style", "tritone")
+	if style != "tritone" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown reharmonization style: " + style})
+		return
+	}
+	var req models.ReharmonizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if chordRootIndex(req.Key) == -1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unrecognised key: " + req.Key})
+		return
+	}
+
+	chords := make([]string, len(req.Chords))
+	for i, ch := range req.Chords {
+		chords[i] = tritoneSub(ch, req.Key)
+	}
+	midi := buildMidi(MidiRequest{
+		Chords:  chords,
+		Tempo:   100,
+		Pattern: "jazz-swing",
+		Octave:  3,
+		Beats:   4,
+	})
+	c.JSON(http.StatusOK, models.ReharmonizeResponse{
+		Key:      req.Key,
+		Style:    style,
+		Original: req.Chords,
+		Chords:   chords,
+		Midi:     midi,
+	})
+}
+
+// inferKeyCandidates is how many keys POST /api/infer-key returns.
+const inferKeyCandidates = 3
+
+// scoreKey counts the chords diatonic to key (compared as simplified triads)
+// and adds half a point each when the progression starts or ends on the tonic.
+func scoreKey(chords []string, key string) (diatonic int, score float64) {
+	triads := map[string]bool{}
+	for _, dc := range diatonicChords(key) {
+		triads[chromatic[dc.root]+dc.suffix] = true
+	}
+	tonic := normalizeChordName(simplifyChord(key))
+	for i, ch := range chords {
+		triad := normalizeChordName(simplifyChord(ch))
+		if triads[triad] {
+			diatonic++
+		}
+		if triad == tonic && (i == 0 || i == len(chords)-1) {
+			score += 0.5
+		}
+	}
+	return diatonic, score + float64(diatonic)
+}
+
+// inferKey scores every major and minor key against chords and returns the
+// best candidates, highest confidence first (major before minor on a tie).
+func inferKey(chords []string) []models.KeyCandidate {
+	type scored struct {
+		models.KeyCandidate
+		score float64
+	}
+	var all []scored
+	for _, minor := range []bool{false, true} {
+		for root := 0; root < 12; root++ {
+			key := spellChord(root, "", flatMajorKeys[root])
+			if minor {
+				key = spellChord(root, "m", flatMajorKeys[(root+3)%12])
+			}
+			diatonic, score := scoreKey(chords, key)
+			all = append(all, scored{models.KeyCandidate{Key: key, Diatonic: diatonic}, score})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].score > all[j].score })
+
+	// A perfect score is every chord diatonic plus the tonic at both ends.
+	best := float64(len(chords)) + 1
+	candidates := make([]models.KeyCandidate, 0, inferKeyCandidates)
+	for _, s := range all[:inferKeyCandidates] {
+		s.Confidence = math.Round(s.score/best*100) / 100
+		candidates = append(candidates, s.KeyCandidate)
+	}
+	return candidates
+}
+
+// InferKey handles POST /api/infer-key, guessing the key of a progression from
+// how many of its chords are diatonic to each of the 24 major and minor keys.
+func InferKey(c *gin.Context) {
+	var req models.InferKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	recognised := false
+	for _, ch := range req.Chords {
+		if chordRootIndex(ch) != -1 {
+			recognised = true
+		}
+	}
+	if !recognised {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no recognisable chords"})
+		return
+	}
+	c.JSON(http.StatusOK, models.InferKeyResponse{Candidates: inferKey(req.Chords)})
+}
+
+// intervalDegrees names each semitone interval as a scale degree relative to the root.
+var intervalDegrees = map[int]string{
+	0: "1", 1: "b2", 2: "2", 3: "b3", 4: "3", 5: "4", 6: "b5",
+	7: "5", 8: "#5", 9: "6", 10: "b7", 11: "7", 14: "9",
+}
+
+// noteLetters are the natural note letters in scale order, and naturalPitch
+// their pitch classes.
+const noteLetters = "CDEFGAB"
+
+var naturalPitch = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+
+// solfegeSyllables are the fixed-do names of the natural notes.
+var solfegeSyllables = map[byte]string{'C': "Do", 'D': "Re", 'E': "Mi", 'F': "Fa", 'G': "Sol", 'A': "La", 'B': "Si"}
+
+// noteNotation reads the ?notation= query of the theory endpoints: "letters"
+// (the default) or "solfege". ok is false, with a 400 sent, for anything else.
+func noteNotation(c *gin.Context) (notation string, ok bool) {
+	notation = c.DefaultQuery("notation", "letters")
+	if notation != "letters" && notation != "solfege" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "notation must be letters or solfege"})
+		return "", false
+	}
+	return notation, true
+}
+
+// labelNote renders a letter note name, or a name starting with one such as
+// "F# Lydian", in notation. Solfege is fixed-do and keeps the accidentals:
+// "C" → "Do", "Bb" → "Sib", "F#" → "Fa#".
+func labelNote(name, notation string) string {
+	if notation != "solfege" || name == "" {
+		return name
+	}
+	if syl, ok := solfegeSyllables[name[0]]; ok {
+		return syl + name[1:]
+	}
+	return name
+}
+
+// labelNotes applies labelNote to each name in place.
+func labelNotes(names []string, notation string) {
+	for i, n := range names {
+		names[i] = labelNote(n, notation)
+	}
+}
+
+// spellInterval names the note semitones above root on the letter its scale
+// degree calls for, so the spelling follows the chord root: a Db chord reads
+// "Db F Ab", G minor "G Bb D" and C7 "C E G Bb". degree is an intervalDegrees value.
+func spellInterval(root string, semitones int, degree string) string {
+	num, _ := strconv.Atoi(strings.TrimLeft(degree, "b#"))
+	letter := noteLetters[(strings.IndexByte(noteLetters, root[0])+num-1)%7]
+	pitch := (chordRootIndex(root) + semitones) % 12
+	diff := ((pitch-naturalPitch[letter])%12 + 12) % 12
+	if diff > 6 {
+		diff -= 12
+	}
+	if diff < 0 {
+		return string(letter) + strings.Repeat("b", -diff)
+	}
+	return string(letter) + strings.Repeat("#", diff)
+}
+
+// GetChordFormula handles GET /api/chord-formula/:chord, describing a chord's
+// quality as semitones, scale degrees and note names (?notation=solfege for
+// fixed-do names).
+func GetChordFormula(c *gin.Context) {
+	notation, ok := noteNotation(c)
+	if !ok {
+		return
+	}
+	chord := c.Param("chord")
+	root := chordRootIndex(chord)
+	if root == -1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unrecognised chord: " + chord})
+		return
+	}
+	quality := chordSuffix(chord)
+	intervals, ok := qualityIntervals[quality]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown chord quality: " + quality})
+		return
+	}
+
+	rootName := chord[:len(chord)-len(quality)]
+	degrees := make([]string, len(intervals))
+	notes := make([]string, len(intervals))
+	for i, iv := range intervals {
+		degrees[i] = intervalDegrees[iv]
+		notes[i] = labelNote(spellInterval(rootName, iv, degrees[i]), notation)
+	}
+	c.JSON(http.StatusOK, models.ChordFormulaResponse{
+		Chord:     chord,
+		Quality:   quality,
+		Semitones: intervals,
+		Degrees:   degrees,
+		Formula:   strings.Join(degrees, " "),
+		Notes:     notes,
+	})
+}
+
+// turnaroundStep is one chord of a turnaround: semitones above the tonic and quality.
+type turnaroundStep struct {
+	offset int
+	suffix string
+}
+
+// turnarounds holds the two-bar turnaround for each style, two beats per chord.
+var turnarounds = map[string][]turnaroundStep{
+	"blues": {{0, "7"}, {5, "7"}, {0, "7"}, {7, "7"}},     // I7-IV7-I7-V7
+	"jazz":  {{0, "maj7"}, {9, "7"}, {2, "m7"}, {7, "7"}}, // Imaj7-VI7-ii7-V7
+}
+
+// turnaroundChords spells the turnaround for style in key, or nil if either is unknown.
+func turnaroundChords(key, style string) []string {
+	tonic := chordRootIndex(key)
+	steps, ok := turnarounds[style]
+	if tonic == -1 || !ok {
+		return nil
+	}
+	flats := keyUsesFlats(key)
+	chords := make([]string, len(steps))
+	for i, st := range steps {
+		chords[i] = spellChord(tonic+st.offset, st.suffix, flats)
+	}
+	return chords
+}
+
+// Turnaround handles POST /api/turnaround?key=E&style=blues, returning the
+// turnaround's chords and a two-bar blues-shuffle MIDI rendering of them.
+func Turnaround(c *gin.Context) {
+	key := c.DefaultQuery("key", "E")
+	style := c.DefaultQuery("style", "blues")
+	if chordRootIndex(key) == -1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unrecognised key: " + key})
+		return
+	}
+	chords := turnaroundChords(key, style)
+	if chords == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown turnaround style: " + style})
+		return
+	}
+
+	midi := buildMidi(MidiRequest{
+		Chords:  chords,
+		Tempo:   100,
+		Pattern: "blues-shuffle",
+		Octave:  3,
+		Beats:   2,
+	})
+	c.JSON(http.StatusOK, models.TurnaroundResponse{
+		Key:    key,
+		Style:  style,
+		Chords: chords,
+		Midi:   midi,
+	})
+}
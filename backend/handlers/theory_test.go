@@ -0,0 +1,665 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"guitartutor/backend/models"
+)
+
+// findSub returns the substitution of the given type, or nil.
+func findSub(subs []models.Substitution, typ string) *models.Substitution {
+	for i := range subs {
+		if subs[i].Type == typ {
+			return &subs[i]
+		}
+	}
+	return nil
+}
+
+// ── keyUsesFlats ──────────────────────────────────────────────────────────
+
+func TestKeyUsesFlats(t *testing.T) {
+	cases := []struct {
+		key  string
+		want bool
+	}{
+		{"C", false}, {"G", false}, {"F", true}, {"Bb", true},
+		{"Dm", true}, {"Em", false}, {"C#", false}, {"Db", true},
+	}
+	for _, tc := range cases {
+		if got := keyUsesFlats(tc.key); got != tc.want {
+			t.Errorf("keyUsesFlats(%q) = %v, want %v", tc.key, got, tc.want)
+		}
+	}
+}
+
+// ── substitutionsFor ──────────────────────────────────────────────────────
+
+func TestSubstitutions_TritoneOfDominant(t *testing.T) {
+	subs := substitutionsFor("G7", "C")
+	tri := findSub(subs, "tritone")
+	if tri == nil {
+		t.Fatal("G7 should have a tritone substitution")
+	}
+	if len(tri.Chords) != 1 || tri.Chords[0] != "Db7" {
+		t.Errorf("tritone sub of G7 = %v, want [Db7]", tri.Chords)
+	}
+	iiV := findSub(subs, "ii-V")
+	if iiV == nil || len(iiV.Chords) != 2 || iiV.Chords[0] != "Dm7" || iiV.Chords[1] != "G7" {
+		t.Errorf("ii-V of G7 = %v, want [Dm7 G7]", iiV)
+	}
+}
+
+func TestSubstitutions_RelativeMinorOfMajor(t *testing.T) {
+	subs := substitutionsFor("C", "C")
+	rel := findSub(subs, "relative-minor")
+	if rel == nil {
+		t.Fatal("C should have a relative-minor substitution")
+	}
+	if rel.Chords[0] != "Am" {
+		t.Errorf("relative minor of C = %v, want [Am]", rel.Chords)
+	}
+	if findSub(subs, "tritone") != nil {
+		t.Error("a major triad should not get a tritone substitution")
+	}
+}
+
+func TestSubstitutions_RelativeMajorOfMinor(t *testing.T) {
+	rel := findSub(substitutionsFor("Dm", "F"), "relative-major")
+	if rel == nil || rel.Chords[0] != "F" {
+		t.Errorf("relative major of Dm = %v, want [F]", rel)
+	}
+}
+
+// ── /api/substitute ───────────────────────────────────────────────────────
+
+func TestSubstitute_Endpoint(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{"chord": "G7", "key": "C"})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/substitute", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/substitute = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp models.SubstituteResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if findSub(resp.Substitutions, "tritone") == nil {
+		t.Error("response missing tritone substitution")
+	}
+}
+
+func TestSubstitute_UnknownKey(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{"chord": "G7", "key": "H"})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/substitute", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown key should return 400, got %d", w.Code)
+	}
+}
+
+// ── diatonicChords / pivotChords ──────────────────────────────────────────
+
+func TestDiatonicChords_Major(t *testing.T) {
+	got := diatonicChords("C")
+	want := []string{"C", "Dm", "Em", "F", "G", "Am", "Bdim"}
+	for i, dc := range got {
+		if name := spellChord(dc.root, dc.suffix, false); name != want[i] {
+			t.Errorf("degree %d = %s, want %s", i+1, name, want[i])
+		}
+	}
+}
+
+func TestDiatonicChords_Minor(t *testing.T) {
+	got := diatonicChords("Am")
+	want := []string{"Am", "Bdim", "C", "Dm", "Em", "F", "G"}
+	for i, dc := range got {
+		if name := spellChord(dc.root, dc.suffix, false); name != want[i] {
+			t.Errorf("degree %d = %s, want %s", i+1, name, want[i])
+		}
+	}
+}
+
+func TestGetPivotChords_CtoG(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/pivot?from=C&to=G", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/pivot = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp models.PivotResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	want := map[string][2]string{
+		"C":  {"I", "IV"},
+		"Em": {"iii", "vi"},
+		"G":  {"V", "I"},
+		"Am": {"vi", "ii"},
+	}
+	if len(resp.Pivots) != len(want) {
+		t.Errorf("got %d pivots, want %d: %v", len(resp.Pivots), len(want), resp.Pivots)
+	}
+	for _, p := range resp.Pivots {
+		labels, ok := want[p.Chord]
+		if !ok {
+			t.Errorf("unexpected pivot chord %s", p.Chord)
+			continue
+		}
+		if p.From != labels[0] || p.To != labels[1] {
+			t.Errorf("%s labelled %s/%s, want %s/%s", p.Chord, p.From, p.To, labels[0], labels[1])
+		}
+	}
+}
+
+func TestGetPivotChords_MissingKey(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/pivot?from=C", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("missing to key should return 400, got %d", w.Code)
+	}
+}
+
+// ── simplifyChord / /api/simplify ─────────────────────────────────────────
+
+func TestSimplifyChord(t *testing.T) {
+	cases := []struct{ chord, want string }{
+		{"Cmaj7", "C"},
+		{"Dm7", "Dm"},
+		{"G7sus4", "G"},
+		{"Bbm9", "Bbm"},
+		{"F#m7b5", "F#dim"},
+		{"Bdim7", "Bdim"},
+		{"Caug", "Caug"},
+		{"C/G", "C"},
+		{"Am/G", "Am"},
+		{"Eadd9", "E"},
+		{"A", "A"},
+	}
+	for _, tc := range cases {
+		if got := simplifyChord(tc.chord); got != tc.want {
+			t.Errorf("simplifyChord(%q) = %q, want %q", tc.chord, got, tc.want)
+		}
+	}
+}
+
+func TestSimplify_Endpoint(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"chords": []string{"Cmaj7", "Dm7", "G7sus4"},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/simplify", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/simplify = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp models.SimplifyResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	want := []string{"C", "Dm", "G"}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(resp.Results), len(want))
+	}
+	for i, r := range resp.Results {
+		if r.Simplified != want[i] {
+			t.Errorf("results[%d] = %q, want %q", i, r.Simplified, want[i])
+		}
+	}
+}
+
+// ── addSeventh / /api/add-sevenths ────────────────────────────────────────
+
+func TestAddSeventh(t *testing.T) {
+	cases := []struct{ chord, key, want string }{
+		{"Em", "C", "Em7"},
+		{"B", "C", "B"}, // non-diatonic
+		{"Bdim", "C", "Bm7b5"},
+		{"Bb", "F", "Bbmaj7"},
+		{"E", "Am", "E"}, // harmonic-minor V isn't in natural minor
+		{"Em", "Am", "Em7"},
+		{"G", "Am", "G7"},
+		{"G7", "C", "G7"},
+	}
+	for _, tc := range cases {
+		if got := addSeventh(tc.chord, tc.key); got != tc.want {
+			t.Errorf("addSeventh(%q, %q) = %q, want %q", tc.chord, tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestAddSevenths_Endpoint(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"key":    "C",
+		"chords": []string{"C", "Am", "F", "G"},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/add-sevenths", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/add-sevenths = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp models.AddSeventhsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	want := []string{"Cmaj7", "Am7", "Fmaj7", "G7"}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(resp.Results), len(want))
+	}
+	for i, r := range resp.Results {
+		if r.Reharmonized != want[i] {
+			t.Errorf("results[%d] = %q, want %q", i, r.Reharmonized, want[i])
+		}
+	}
+}
+
+// ── toParallel / /api/parallel ────────────────────────────────────────────
+
+func TestToParallel(t *testing.T) {
+	cases := []struct{ chord, key, want string }{
+		{"Em", "C", "Eb"},
+		{"Am", "C", "Ab"},
+		{"G7", "C", "Gm7"},
+		{"Cmaj7", "C", "Cm7"},
+		{"D7", "C", "D7"}, // secondary dominant, not diatonic
+		{"Am", "Am", "A"},
+		{"G", "Am", "G#dim"},
+		{"Bdim", "Am", "Bm"},
+	}
+	for _, tc := range cases {
+		if got := toParallel(tc.chord, tc.key); got != tc.want {
+			t.Errorf("toParallel(%q, %q) = %q, want %q", tc.chord, tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestParallel_Endpoint(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"key":    "C",
+		"chords": []string{"C", "F", "G"},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/parallel", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/parallel = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp models.ParallelResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.ParallelKey != "Cm" {
+		t.Errorf("parallelKey = %q, want Cm", resp.ParallelKey)
+	}
+	want := []string{"Cm", "Fm", "Gm"}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(resp.Results), len(want))
+	}
+	for i, r := range resp.Results {
+		if r.Reharmonized != want[i] {
+			t.Errorf("results[%d] = %q, want %q", i, r.Reharmonized, want[i])
+		}
+	}
+}
+
+// ── soloModeFor / /api/solo-guide ─────────────────────────────────────────
+
+func TestSoloModeFor(t *testing.T) {
+	cases := []struct {
+		chord, key string
+		mode       string
+		diatonic   bool
+	}{
+		{"Em", "C", "Phrygian", true},
+		{"F", "C", "Lydian", true},
+		{"Bm7b5", "C", "Locrian", true},
+		{"C7", "C", "Mixolydian", false}, // b7 isn't in C major
+		{"E7", "Am", "Mixolydian", false},
+		{"Am", "Am", "Aeolian", true},
+		{"Dm", "Am", "Dorian", true},
+		{"Ebm", "C", "Dorian", false},
+	}
+	for _, tc := range cases {
+		m, diatonic := soloModeFor(tc.chord, tc.key)
+		if modes[m].name != tc.mode || diatonic != tc.diatonic {
+			t.Errorf("soloModeFor(%q, %q) = %s/%v, want %s/%v", tc.chord, tc.key, modes[m].name, diatonic, tc.mode, tc.diatonic)
+		}
+	}
+}
+
+func TestSoloGuide_TwoFiveOne(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"key":    "C",
+		"chords": []string{"Dm7", "G7", "Cmaj7"},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/solo-guide", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/solo-guide = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp models.SoloGuideResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	want := []struct {
+		scale   string
+		tones   []string
+		passing []string
+	}{
+		{"D Dorian", []string{"D", "F", "A", "C"}, []string{"E", "G", "B"}},
+		{"G Mixolydian", []string{"G", "B", "D", "F"}, []string{"A", "C", "E"}},
+		{"C Ionian", []string{"C", "E", "G", "B"}, []string{"D", "F", "A"}},
+	}
+	if len(resp.Chords) != len(want) {
+		t.Fatalf("got %d chords, want %d", len(resp.Chords), len(want))
+	}
+	for i, w := range want {
+		got := resp.Chords[i]
+		if got.Scale != w.scale {
+			t.Errorf("chords[%d].scale = %q, want %q", i, got.Scale, w.scale)
+		}
+		if !reflect.DeepEqual(got.ChordTones, w.tones) {
+			t.Errorf("chords[%d].chordTones = %v, want %v", i, got.ChordTones, w.tones)
+		}
+		if !reflect.DeepEqual(got.PassingTones, w.passing) {
+			t.Errorf("chords[%d].passingTones = %v, want %v", i, got.PassingTones, w.passing)
+		}
+	}
+}
+
+// ── /api/reharmonize ──────────────────────────────────────────────────────
+
+func TestReharmonize_TritoneSubs(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"key":    "C",
+		"chords": []string{"Dm7", "G7", "Cmaj7"},
+	})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/reharmonize?style=tritone", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/reharmonize = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp models.ReharmonizeResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if want := []string{"Dm7", "G7", "Cmaj7"}; !reflect.DeepEqual(resp.Original, want) {
+		t.Errorf("original = %v, want %v", resp.Original, want)
+	}
+	if want := []string{"Dm7", "Db7", "Cmaj7"}; !reflect.DeepEqual(resp.Chords, want) {
+		t.Errorf("chords = %v, want %v", resp.Chords, want)
+	}
+
+	// The second bar is built on Db3 (49) instead of G3.
+	bar := uint32(4 * ticksPerQuarter)
+	lowest := byte(127)
+	for _, ev := range parseTrack(trackChunks(t, resp.Midi)[0]) {
+		if ev.status&0xF0 == 0x90 && ev.data2 > 0 && ev.tick >= bar && ev.tick < 2*bar {
+			lowest = min(lowest, ev.data1)
+		}
+	}
+	if lowest != 49 {
+		t.Errorf("bar 2 lowest note = %d, want Db3 (49)", lowest)
+	}
+}
+
+func TestReharmonize_UnknownStyle(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{"key": "C", "chords": []string{"G7"}})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/reharmonize?style=backdoor", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("style=backdoor = %d, want 400", w.Code)
+	}
+}
+
+// ── /api/infer-key ────────────────────────────────────────────────────────
+
+func postInferKey(t *testing.T, chords []string) (int, models.InferKeyResponse) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"chords": chords})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/infer-key", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	var resp models.InferKeyResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestInferKey_PopProgression(t *testing.T) {
+	code, resp := postInferKey(t, []string{"C", "Am", "F", "G"})
+	if code != http.StatusOK {
+		t.Fatalf("POST /api/infer-key = %d, want 200", code)
+	}
+	if len(resp.Candidates) != 3 {
+		t.Fatalf("got %d candidates, want 3", len(resp.Candidates))
+	}
+	top, second := resp.Candidates[0], resp.Candidates[1]
+	if top.Key != "C" || top.Diatonic != 4 || top.Confidence != 0.9 {
+		t.Errorf("top = %+v, want C with 4 diatonic chords at 0.9", top)
+	}
+	if second.Key != "Am" || second.Confidence >= top.Confidence {
+		t.Errorf("second = %+v, want Am just below C", second)
+	}
+}
+
+func TestInferKey_MinorWithFlats(t *testing.T) {
+	_, resp := postInferKey(t, []string{"Gm", "Eb", "Bb", "F", "Gm7"})
+	if top := resp.Candidates[0]; top.Key != "Gm" {
+		t.Errorf("top = %+v, want Gm", top)
+	}
+}
+
+func TestInferKey_NoRecognisableChords(t *testing.T) {
+	if code, _ := postInferKey(t, []string{"X", "?"}); code != http.StatusBadRequest {
+		t.Errorf("unrecognisable chords = %d, want 400", code)
+	}
+}
+
+// ── /api/chord-formula ────────────────────────────────────────────────────
+
+func getChordFormula(t *testing.T, chord string) (int, models.ChordFormulaResponse) {
+	t.Helper()
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/chord-formula/"+url.PathEscape(chord), nil)
+	r.ServeHTTP(w, req)
+	var resp models.ChordFormulaResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestGetChordFormula_Triad(t *testing.T) {
+	code, resp := getChordFormula(t, "Am")
+	if code != http.StatusOK {
+		t.Fatalf("GET /api/chord-formula/Am = %d, want 200", code)
+	}
+	if resp.Formula != "1 b3 5" {
+		t.Errorf("formula = %q, want \"1 b3 5\"", resp.Formula)
+	}
+	if strings.Join(resp.Notes, " ") != "A C E" {
+		t.Errorf("notes = %v, want [A C E]", resp.Notes)
+	}
+}
+
+func TestGetChordFormula_Seventh(t *testing.T) {
+	code, resp := getChordFormula(t, "Cmaj7")
+	if code != http.StatusOK {
+		t.Fatalf("GET /api/chord-formula/Cmaj7 = %d, want 200", code)
+	}
+	if resp.Formula != "1 3 5 7" {
+		t.Errorf("formula = %q, want \"1 3 5 7\"", resp.Formula)
+	}
+	want := []int{0, 4, 7, 11}
+	for i, st := range want {
+		if resp.Semitones[i] != st {
+			t.Errorf("semitones = %v, want %v", resp.Semitones, want)
+			break
+		}
+	}
+
+	_, flat := getChordFormula(t, "Bb7")
+	if strings.Join(flat.Notes, " ") != "Bb D F Ab" {
+		t.Errorf("Bb7 notes = %v, want [Bb D F Ab]", flat.Notes)
+	}
+}
+
+func TestGetChordFormula_FollowsRootSpelling(t *testing.T) {
+	cases := map[string]string{
+		"Db":    "Db F Ab",
+		"Gm":    "G Bb D",
+		"C7":    "C E G Bb",
+		"F#dim": "F# A C",
+		"Ebm7":  "Eb Gb Bb Db",
+		"A7":    "A C# E G",
+	}
+	for chord, want := range cases {
+		_, resp := getChordFormula(t, chord)
+		if got := strings.Join(resp.Notes, " "); got != want {
+			t.Errorf("%s notes = %q, want %q", chord, got, want)
+		}
+	}
+}
+
+func TestSpellInterval(t *testing.T) {
+	cases := []struct {
+		root      string
+		semitones int
+		degree    string
+		want      string
+	}{
+		{"Db", 0, "1", "Db"},
+		{"Db", 4, "3", "F"},
+		{"Db", 7, "5", "Ab"},
+		{"B", 4, "3", "D#"},
+		{"C", 14, "9", "D"},
+		{"Cb", 3, "b3", "Ebb"},
+	}
+	for _, c := range cases {
+		if got := spellInterval(c.root, c.semitones, c.degree); got != c.want {
+			t.Errorf("spellInterval(%q, %d, %q) = %q, want %q", c.root, c.semitones, c.degree, got, c.want)
+		}
+	}
+}
+
+func TestTheory_SolfegeNotation(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{"key": "C", "chords": []string{"C"}})
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/solo-guide?notation=solfege", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/solo-guide?notation=solfege = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var guide models.SoloGuideResponse
+	json.Unmarshal(w.Body.Bytes(), &guide)
+	if len(guide.Chords) != 1 {
+		t.Fatalf("got %d chords, want 1", len(guide.Chords))
+	}
+	if got := strings.Join(guide.Chords[0].ScaleNotes, " "); got != "Do Re Mi Fa Sol La Si" {
+		t.Errorf("C major scale = %q, want \"Do Re Mi Fa Sol La Si\"", got)
+	}
+	if guide.Chords[0].Scale != "Do Ionian" {
+		t.Errorf("scale = %q, want \"Do Ionian\"", guide.Chords[0].Scale)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/chord-formula/Bb7?notation=solfege", nil)
+	r.ServeHTTP(w, req)
+	var formula models.ChordFormulaResponse
+	json.Unmarshal(w.Body.Bytes(), &formula)
+	if got := strings.Join(formula.Notes, " "); got != "Sib Re Fa Lab" {
+		t.Errorf("Bb7 notes = %q, want \"Sib Re Fa Lab\"", got)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/chord-formula/C?notation=roman", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("notation=roman = %d, want 400", w.Code)
+	}
+}
+
+func TestGetChordFormula_UnknownQuality(t *testing.T) {
+	if code, _ := getChordFormula(t, "Cblah"); code != http.StatusBadRequest {
+		t.Errorf("unknown quality = %d, want 400", code)
+	}
+}
+
+// ── /api/turnaround ───────────────────────────────────────────────────────
+
+func TestTurnaround_BluesInE(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/turnaround?key=E&style=blues", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/turnaround = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp models.TurnaroundResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	want := []string{"E7", "A7", "E7", "B7"}
+	if strings.Join(resp.Chords, " ") != strings.Join(want, " ") {
+		t.Errorf("chords = %v, want %v", resp.Chords, want)
+	}
+	if len(resp.Midi) < 4 || string(resp.Midi[:4]) != "MThd" {
+		t.Error("midi field is not a valid MIDI file")
+	}
+}
+
+func TestTurnaroundChords_JazzFlatKey(t *testing.T) {
+	got := turnaroundChords("Bb", "jazz")
+	want := []string{"Bbmaj7", "G7", "Cm7", "F7"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("turnaroundChords(Bb, jazz) = %v, want %v", got, want)
+	}
+}
+
+func TestTurnaround_UnknownStyle(t *testing.T) {
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/turnaround?key=E&style=polka", nil)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown style should return 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"guitartutor/backend/models"
+)
+
+// validateVariant returns the problems with one chord variant on inst.
+// Fretboard variants need a fret and finger entry per string, each fret "x"
+// or a non-negative integer; keyboard variants need keys that parse.
+func validateVariant(inst models.Instrument, v models.ChordVariant) []string {
+	var errs []string
+	if inst.DisplayType == "keyboard" {
+		if len(v.Keys) == 0 {
+			errs = append(errs, "keys is empty")
+		}
+		for i, k := range v.Keys {
+			if keyNameToMidi(k) == -1 {
+				errs = append(errs, fmt.Sprintf("keys[%d]: invalid key %q", i, k))
+			}
+		}
+		return errs
+	}
+	if len(v.Frets) != inst.Strings {
+		errs = append(errs, fmt.Sprintf("frets has %d entries but %s has %d strings", len(v.Frets), inst.Key, inst.Strings))
+	}
+	if len(v.Fingers) != inst.Strings {
+		errs = append(errs, fmt.Sprintf("fingers has %d entries but %s has %d strings", len(v.Fingers), inst.Key, inst.Strings))
+	}
+	for i, fv := range v.Frets {
+		if fv == "x" {
+			continue
+		}
+		if fret, err := strconv.Atoi(fv); err != nil || fret < 0 {
+			errs = append(errs, fmt.Sprintf("frets[%d]: invalid fret %q", i, fv))
+		}
+	}
+	return errs
+}
+
+// validateDiagrams checks every variant of every chord, reporting errors in
+// chord-name then variant order.
+func validateDiagrams(inst models.Instrument, diagrams models.ChordDiagrams) []models.DiagramError {
+	chords := make([]string, 0, len(diagrams))
+	for chord := range diagrams {
+		chords = append(chords, chord)
+	}
+	sort.Strings(chords)
+	errs := []models.DiagramError{}
+	for _, chord := range chords {
+		for vi, v := range diagrams[chord] {
+			for _, e := range validateVariant(inst, v) {
+				errs = append(errs, models.DiagramError{Chord: chord, Variant: vi, Error: e})
+			}
+		}
+	}
+	return errs
+}
+
+// ValidateDiagrams handles POST /api/validate-diagrams?instrument=…, checking a
+// contributor's chord JSON against the instrument before it is added to the data.
+func ValidateDiagrams(c *gin.Context) {
+	inst, err := findInstrument(c.Query("instrument"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var diagrams models.ChordDiagrams
+	if err := c.ShouldBindJSON(&diagrams); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	errs := validateDiagrams(inst, diagrams)
+	c.JSON(http.StatusOK, models.ValidateDiagramsResponse{Valid: len(errs) == 0, Errors: errs})
+}
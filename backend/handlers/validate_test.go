@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"guitartutor/backend/models"
+)
+
+func TestValidateDiagrams_MalformedVariant(t *testing.T) {
+	body := `{"C": [
+		{"name": "Open", "frets": ["x","3","2","0","1","0"], "fingers": ["","3","2","","1",""]},
+		{"name": "Broken", "frets": ["x","3","2","0","-1"], "fingers": ["","3","2","","1",""]}
+	]}`
+	r := newRouter()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/validate-diagrams?instrument=guitar", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/validate-diagrams = %d, want 200; body: %s", w.Code, w.Body)
+	}
+	var resp models.ValidateDiagramsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	want := []models.DiagramError{
+		{Chord: "C", Variant: 1, Error: "frets has 5 entries but guitar has 6 strings"},
+		{Chord: "C", Variant: 1, Error: `frets[4]: invalid fret "-1"`},
+	}
+	if resp.Valid || len(resp.Errors) != len(want) {
+		t.Fatalf("response = %+v, want invalid with %d errors", resp, len(want))
+	}
+	for i := range want {
+		if resp.Errors[i] != want[i] {
+			t.Errorf("errors[%d] = %+v, want %+v", i, resp.Errors[i], want[i])
+		}
+	}
+}
+
+func TestValidateDiagrams_EmbeddedDataIsValid(t *testing.T) {
+	instruments, err := loadInstruments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, inst := range instruments {
+		diagrams, err := loadChordDiagrams(inst.Key)
+		if err != nil {
+			continue
+		}
+		for _, e := range validateDiagrams(inst, diagrams) {
+			t.Errorf("%s %s[%d]: %s", inst.Key, e.Chord, e.Variant, e.Error)
+		}
+	}
+}
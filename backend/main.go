@@ -1,10 +1,18 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -12,36 +20,176 @@ import (
 	"guitartutor/backend/handlers"
 )
 
+// shutdownTimeout bounds how long a SIGTERM or SIGINT waits for in-flight
+// requests, such as a long MIDI render, before the server exits anyway.
+const shutdownTimeout = 30 * time.Second
+
+// defaultDrainDelay is how long the server keeps answering, with /health
+// failing, before it stops accepting connections. Override it with
+// SHUTDOWN_DRAIN_DELAY (a Go duration such as "10s"; "0" skips the wait).
+const defaultDrainDelay = 5 * time.Second
+
+// draining is set once shutdown begins, so /health fails and load balancers
+// stop routing here before the listener closes.
+var draining atomic.Bool
+
+// health answers GET /health: 200 normally, 503 once the server is draining.
+func health(c *gin.Context) {
+	if draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting down"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
 func main() {
+	// Structured JSON logs; handlers add request_id/endpoint via the RequestID middleware.
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
 	r := gin.Default()
 
 	// CORS — origins configurable via CORS_ORIGINS env var (comma-separated).
 	// Defaults to * for local development; set a specific origin in production.
-	originsEnv := os.Getenv("CORS_ORIGINS")
-	if originsEnv == "" {
-		originsEnv = "*"
+	origins, invalid := parseCORSOrigins(os.Getenv("CORS_ORIGINS"))
+	for _, o := range invalid {
+		slog.Warn("ignoring invalid CORS origin", "origin", o)
+	}
+	if len(origins) == 0 {
+		slog.Error("CORS_ORIGINS has no valid origins")
+		os.Exit(1)
 	}
 	r.Use(cors.New(cors.Config{
-		AllowOrigins: strings.Split(originsEnv, ","),
-		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodOptions},
-		AllowHeaders: []string{"Origin", "Content-Type"},
+		AllowOrigins:  origins,
+		AllowMethods:  []string{http.MethodGet, http.MethodPost, http.MethodOptions},
+		AllowHeaders:  []string{"Origin", "Content-Type", handlers.RequestIDHeader},
+		ExposeHeaders: []string{handlers.RequestIDHeader},
 	}))
+	r.Use(handlers.RequestID())
+	r.Use(handlers.Gzip())
 
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
-	})
+	r.GET("/health", health)
 
 	api := r.Group("/api")
 	{
+		api.GET("/version", handlers.GetVersion)
 		api.GET("/instruments", handlers.GetInstruments)
+		api.GET("/open-strings/:instrument", handlers.GetOpenStrings)
 		api.GET("/progressions", handlers.GetProgressions)
+		api.GET("/progressions/:name/midi", handlers.GetProgressionMidi)
+		api.POST("/progressions/:name/transpose", handlers.TransposeProgression)
+		api.GET("/genres", handlers.GetGenres)
 		api.GET("/chords/:instrument", handlers.GetChords)
+		api.GET("/chords/:instrument/instruments", handlers.GetChordInstruments) // :instrument holds the chord name here
+		api.GET("/chords/:instrument/:chord/midi", handlers.GetChordMidi)
+		api.GET("/chords/:instrument/:chord/midi-all", handlers.GetChordMidiAll)
 		api.POST("/chords/batch", handlers.BatchChords)
+		api.POST("/position-voicings", handlers.PositionVoicings)
+		api.POST("/chords/compare", handlers.CompareChords)
 		api.POST("/transpose", handlers.Transpose)
+		api.POST("/transpose/diff", handlers.TransposeDiff)
+		api.POST("/learn-order", handlers.LearnOrder)
+		api.POST("/shift-frets", handlers.ShiftFrets)
+		api.POST("/substitute", handlers.Substitute)
+		api.POST("/simplify", handlers.Simplify)
+		api.POST("/add-sevenths", handlers.AddSevenths)
+		api.POST("/parallel", handlers.Parallel)
+		api.POST("/solo-guide", handlers.SoloGuide)
+		api.GET("/pivot", handlers.GetPivotChords)
+		api.GET("/chord-formula/:chord", handlers.GetChordFormula)
+		api.GET("/caged/:chord", handlers.GetCagedShapes)
+		api.POST("/turnaround", handlers.Turnaround)
+		api.POST("/reharmonize", handlers.Reharmonize)
+		api.POST("/infer-key", handlers.InferKey)
 		api.POST("/midi", handlers.GenerateMidi)
+		api.POST("/midi/events", handlers.GetMidiEvents)
+		api.POST("/quantize", handlers.QuantizeEvents)
+		api.POST("/export/abc", handlers.ExportABC)
+		api.POST("/song", handlers.GenerateSong)
+		api.POST("/tempo-ladder", handlers.GenerateTempoLadder)
+		api.POST("/interval-midi", handlers.GenerateIntervalMidi)
+		api.POST("/validate-diagrams", handlers.ValidateDiagrams)
+	}
+
+	drainDelay := defaultDrainDelay
+	if v := os.Getenv("SHUTDOWN_DRAIN_DELAY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			slog.Error("invalid SHUTDOWN_DRAIN_DELAY", "value", v)
+			os.Exit(1)
+		}
+		drainDelay = d
+	}
+
+	ln, err := net.Listen("tcp", ":8080")
+	if err != nil {
+		slog.Error("server failed to start", "error", err)
+		os.Exit(1)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := serve(ctx, ln, r, drainDelay, shutdownTimeout); err != nil {
+		slog.Error("server stopped", "error", err)
+		os.Exit(1)
 	}
+}
 
-	if err := r.Run(":8080"); err != nil {
-		log.Fatalf("server failed to start: %v", err)
+// serve runs h on ln until ctx is cancelled. It then marks the server
+// draining and keeps serving for drainDelay, so health checks see the 503 and
+// traffic moves elsewhere, before it stops accepting connections and waits up
+// to timeout for in-flight requests to complete. It returns nil after a clean
+// shutdown.
+func serve(ctx context.Context, ln net.Listener, h http.Handler, drainDelay, timeout time.Duration) error {
+	srv := &http.Server{Handler: h}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	draining.Store(true)
+	slog.Info("draining", "delay", drainDelay.String())
+	select {
+	case err := <-errc:
+		return err
+	case <-time.After(drainDelay):
+	}
+	slog.Info("shutting down", "timeout", timeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// parseCORSOrigins splits a comma-separated CORS_ORIGINS value into origins,
+// trimming spaces and dropping empty entries. Anything other than "*" or an
+// http(s) scheme and host with no path is returned in invalid instead. An
+// unset or blank value allows every origin.
+func parseCORSOrigins(env string) (origins, invalid []string) {
+	if strings.TrimSpace(env) == "" {
+		return []string{"*"}, nil
+	}
+	for _, o := range strings.Split(env, ",") {
+		o = strings.TrimSpace(o)
+		if o == "" {
+			continue
+		}
+		if o == "*" {
+			origins = append(origins, o)
+			continue
+		}
+		u, err := url.Parse(o)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			invalid = append(invalid, o)
+			continue
+		}
+		origins = append(origins, strings.TrimSuffix(o, "/"))
 	}
+	return origins, invalid
 }
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseCORSOrigins(t *testing.T) {
+	cases := []struct {
+		env     string
+		origins []string
+		invalid []string
+	}{
+		{"", []string{"*"}, nil},
+		{"   ", []string{"*"}, nil},
+		{"*", []string{"*"}, nil},
+		{"https://a.example, https://b.example", []string{"https://a.example", "https://b.example"}, nil},
+		{" https://a.example ,, ", []string{"https://a.example"}, nil},
+		{"https://a.example/", []string{"https://a.example"}, nil},
+		{"http://localhost:3000,a.example,ftp://x,https://x/app", []string{"http://localhost:3000"}, []string{"a.example", "ftp://x", "https://x/app"}},
+	}
+	for _, c := range cases {
+		origins, invalid := parseCORSOrigins(c.env)
+		if !reflect.DeepEqual(origins, c.origins) || !reflect.DeepEqual(invalid, c.invalid) {
+			t.Errorf("parseCORSOrigins(%q) = %q, %q; want %q, %q", c.env, origins, invalid, c.origins, c.invalid)
+		}
+	}
+}
+
+func TestServe_GracefulShutdown(t *testing.T) {
+	t.Cleanup(func() { draining.Store(false) })
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := "http://" + ln.Addr().String()
+	started, release := make(chan struct{}), make(chan struct{})
+	r := gin.New()
+	r.GET("/health", health)
+	r.GET("/slow", func(c *gin.Context) {
+		close(started)
+		<-release // an in-flight render
+		c.String(http.StatusOK, "done")
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- serve(ctx, ln, r, 300*time.Millisecond, 5*time.Second) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(addr + "/slow")
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		inFlight <- result{string(b), err}
+	}()
+	<-started
+	cancel()
+
+	// During the drain delay new probes still connect and see the 503.
+	var code int
+	for deadline := time.Now().Add(200 * time.Millisecond); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		resp, err := http.Get(addr + "/health")
+		if err != nil {
+			t.Fatalf("health probe during drain delay: %v", err)
+		}
+		resp.Body.Close()
+		if code = resp.StatusCode; code == http.StatusServiceUnavailable {
+			break
+		}
+	}
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("/health while draining = %d, want 503", code)
+	}
+
+	// After the delay, shutdown waits for the in-flight request while
+	// refusing new connections.
+	time.Sleep(300 * time.Millisecond)
+	select {
+	case err := <-served:
+		t.Fatalf("serve returned %v with a request still in flight", err)
+	default:
+	}
+	if _, err := net.DialTimeout("tcp", ln.Addr().String(), time.Second); err == nil {
+		t.Error("new connection accepted after the drain delay")
+	}
+
+	close(release)
+	if res := <-inFlight; res.err != nil || res.body != "done" {
+		t.Errorf("in-flight request = %q, %v; want it to complete", res.body, res.err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("serve = %v, want nil after a clean shutdown", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after the last request finished")
+	}
+}
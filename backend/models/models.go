@@ -1,5 +1,11 @@
 package models
 
+// VersionResponse identifies the build and the embedded data it serves.
+type VersionResponse struct {
+	Version      string `json:"version"`
+	DataChecksum string `json:"dataChecksum"`
+}
+
 // Instrument describes a string instrument supported by the app.
 type Instrument struct {
 	Key         string   `json:"key"`
@@ -9,6 +15,8 @@ type Instrument struct {
 	OpenMidi    []int    `json:"openMidi"`    // MIDI note for each open string (fretboard instruments only)
 	Icon        string   `json:"icon"`
 	DisplayType string   `json:"displayType"` // "fretboard" or "keyboard"
+	MinMidi     int      `json:"minMidi"`     // lowest playable MIDI note
+	MaxMidi     int      `json:"maxMidi"`     // highest playable MIDI note
 }
 
 // FeaturedSong describes a song that uses a progression.
@@ -23,6 +31,8 @@ type Progression struct {
 	Name        string         `json:"name"`
 	Chords      []string       `json:"chords"`
 	OriginalKey string         `json:"originalKey"`
+	Level       string         `json:"level"`           // "beginner", "intermediate" or "advanced"
+	Genre       string         `json:"genre,omitempty"` // e.g. "pop", "blues", "jazz"
 	Description string         `json:"description"`
 	Songs       []FeaturedSong `json:"songs"`
 }
@@ -36,11 +46,30 @@ type ChordVariant struct {
 	Fingers  []string `json:"fingers,omitempty"`
 	Position int      `json:"position,omitempty"`
 	Keys     []string `json:"keys,omitempty"` // piano: MIDI-style note names, e.g. "C4", "F#3"
+
+	// Computed when served, not stored in the chord data.
+	SoundingStrings int `json:"soundingStrings"`       // non-muted strings, or key count for piano
+	LowestFret      int `json:"lowestFret,omitempty"`  // lowest fretted (non-open) position
+	HighestFret     int `json:"highestFret,omitempty"` // highest fretted position
 }
 
 // ChordDiagrams maps chord name → slice of variants.
 type ChordDiagrams map[string][]ChordVariant
 
+// OpenStringsResponse lists an instrument's open-string pitches in a tuning.
+type OpenStringsResponse struct {
+	Instrument string   `json:"instrument"`
+	Tuning     string   `json:"tuning"`
+	OpenMidi   []int    `json:"openMidi"` // low string first, ready for /api/midi
+	Notes      []string `json:"notes"`    // e.g. ["E2","A2","D3","G3","B3","E4"]
+}
+
+// ChordInstrumentsResponse lists the instruments with a diagram for a chord.
+type ChordInstrumentsResponse struct {
+	Chord       string   `json:"chord"`
+	Instruments []string `json:"instruments"`
+}
+
 // BatchChordsRequest asks for diagrams for a list of chord names on one instrument.
 type BatchChordsRequest struct {
 	Instrument string   `json:"instrument" binding:"required"`
@@ -50,11 +79,54 @@ type BatchChordsRequest struct {
 // BatchChordsResponse maps each requested chord name to its variants.
 type BatchChordsResponse map[string][]ChordVariant
 
+// OrderedChordVariants is one entry of the ordered batch response
+// (POST /api/chords/batch?ordered=true), which keeps the request's order.
+type OrderedChordVariants struct {
+	Chord    string         `json:"chord"`
+	Variants []ChordVariant `json:"variants"`
+}
+
+// PositionVoicingsRequest asks for the voicings of each chord that can be
+// played without leaving a fret window, open strings allowed.
+type PositionVoicingsRequest struct {
+	Instrument string   `json:"instrument" binding:"required"`
+	Chords     []string `json:"chords"     binding:"required"`
+	MinFret    int      `json:"minFret"` // lowest fret the hand covers, e.g. 0
+	MaxFret    int      `json:"maxFret"` // highest fret the hand covers, e.g. 4
+}
+
+// CompareChordsRequest asks for the note overlap between two chord voicings.
+type CompareChordsRequest struct {
+	Instrument string `json:"instrument" binding:"required"`
+	ChordA     string `json:"chordA"     binding:"required"`
+	ChordB     string `json:"chordB"     binding:"required"`
+	VariantA   int    `json:"variantA"`
+	VariantB   int    `json:"variantB"`
+}
+
+// CompareChordsResponse lists each voicing's MIDI notes and the pitch classes
+// they share or hold alone.
+type CompareChordsResponse struct {
+	NotesA []int    `json:"notesA"` // sorted MIDI notes of chordA's voicing
+	NotesB []int    `json:"notesB"`
+	Common []string `json:"common"` // pitch classes in both, e.g. ["C","E"]
+	OnlyA  []string `json:"onlyA"`
+	OnlyB  []string `json:"onlyB"`
+}
+
 // TransposeRequest asks to transpose a list of chords from one key to another.
 type TransposeRequest struct {
-	FromKey string   `json:"from_key" binding:"required"`
-	ToKey   string   `json:"to_key"   binding:"required"`
-	Chords  []string `json:"chords"   binding:"required"`
+	FromKey    string   `json:"from_key" binding:"required"`
+	ToKey      string   `json:"to_key"   binding:"required"`
+	Chords     []string `json:"chords"   binding:"required"`
+	Capo       int      `json:"capo"`       // optional capo fret the transposed shapes are played at
+	Instrument string   `json:"instrument"` // optional; enables range warnings for the moved voicings
+}
+
+// ProgressionTransposeRequest is the JSON body for
+// POST /api/progressions/:name/transpose.
+type ProgressionTransposeRequest struct {
+	ToKey string `json:"to_key" binding:"required"`
 }
 
 // TransposedChord holds the original and transposed name of a single chord.
@@ -65,6 +137,249 @@ type TransposedChord struct {
 
 // TransposeResponse is the result of a batch transpose operation.
 type TransposeResponse struct {
-	Semitones int               `json:"semitones"`
-	Results   []TransposedChord `json:"results"`
+	Semitones   int               `json:"semitones"`
+	Results     []TransposedChord `json:"results"`
+	SoundingKey string            `json:"sounding_key,omitempty"` // to_key raised by the capo; only set when capo > 0
+	Warnings    []string          `json:"warnings,omitempty"`     // voicings pushed very high on the instrument
+}
+
+// TransposeDiffRequest asks how much of a song's fingering changes when it is
+// transposed from one key to another on an instrument.
+type TransposeDiffRequest struct {
+	FromKey    string   `json:"from_key"   binding:"required"`
+	ToKey      string   `json:"to_key"     binding:"required"`
+	Chords     []string `json:"chords"     binding:"required"`
+	Instrument string   `json:"instrument" binding:"required"`
+}
+
+// ChordShapeChange compares the primary diagram of a chord before and after
+// transposition. Difficulty runs from 1 (open chord) to 5 (barre high up).
+type ChordShapeChange struct {
+	Original             string `json:"original"`
+	Transposed           string `json:"transposed"`
+	OriginalDifficulty   int    `json:"originalDifficulty"`   // 0 when there is no diagram
+	TransposedDifficulty int    `json:"transposedDifficulty"` // 0 when there is no diagram
+	Changed              bool   `json:"changed"`
+	Reason               string `json:"reason,omitempty"` // why the change needs relearning
+}
+
+// TransposeDiffResponse lists the per-chord changes and how many need relearning.
+type TransposeDiffResponse struct {
+	Semitones int                `json:"semitones"`
+	Chords    []ChordShapeChange `json:"chords"`
+	Changed   int                `json:"changed"`
+}
+
+// LearnOrderRequest asks for the order in which to teach a song's chords.
+type LearnOrderRequest struct {
+	Instrument string   `json:"instrument" binding:"required"`
+	Chords     []string `json:"chords"     binding:"required"`
+}
+
+// LearnOrderChord is one chord to introduce, rated like ChordShapeChange.
+type LearnOrderChord struct {
+	Chord      string `json:"chord"`
+	Difficulty int    `json:"difficulty"`
+}
+
+// LearnOrderResponse lists the distinct chords easiest first, and separately
+// those with no diagram on the instrument.
+type LearnOrderResponse struct {
+	Chords  []LearnOrderChord `json:"chords"`
+	Missing []string          `json:"missing"`
+}
+
+// SubstituteRequest asks for reharmonisation options for one chord in a key.
+type SubstituteRequest struct {
+	Chord string `json:"chord" binding:"required"`
+	Key   string `json:"key"   binding:"required"`
+}
+
+// Substitution is one suggested replacement: a single chord or a short sequence.
+type Substitution struct {
+	Type        string   `json:"type"` // "relative-minor", "relative-major", "tritone", "ii-V"
+	Chords      []string `json:"chords"`
+	Description string   `json:"description"`
+}
+
+// SubstituteResponse lists the substitutions available for the requested chord.
+type SubstituteResponse struct {
+	Chord         string         `json:"chord"`
+	Key           string         `json:"key"`
+	Substitutions []Substitution `json:"substitutions"`
+}
+
+// PivotChord is a chord diatonic to two keys, with its Roman-numeral function in each.
+type PivotChord struct {
+	Chord string `json:"chord"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// PivotResponse lists the pivot chords available when modulating between two keys.
+type PivotResponse struct {
+	From   string       `json:"from"`
+	To     string       `json:"to"`
+	Pivots []PivotChord `json:"pivots"`
+}
+
+// SimplifyRequest asks to reduce a list of chords to beginner triads.
+type SimplifyRequest struct {
+	Chords []string `json:"chords" binding:"required"`
+}
+
+// SimplifiedChord holds the original and simplified name of a single chord.
+type SimplifiedChord struct {
+	Original   string `json:"original"`
+	Simplified string `json:"simplified"`
+}
+
+// SimplifyResponse is the result of a batch simplify operation.
+type SimplifyResponse struct {
+	Results []SimplifiedChord `json:"results"`
+}
+
+// AddSeventhsRequest asks to upgrade a progression's triads to diatonic sevenths.
+type AddSeventhsRequest struct {
+	Key    string   `json:"key"    binding:"required"`
+	Chords []string `json:"chords" binding:"required"`
+}
+
+// ReharmonizedChord holds the original and reharmonized name of a single chord.
+type ReharmonizedChord struct {
+	Original     string `json:"original"`
+	Reharmonized string `json:"reharmonized"`
+}
+
+// AddSeventhsResponse is the result of an add-sevenths operation.
+type AddSeventhsResponse struct {
+	Key     string              `json:"key"`
+	Results []ReharmonizedChord `json:"results"`
+}
+
+// ParallelRequest asks to move a progression into its parallel major/minor key.
+type ParallelRequest struct {
+	Key    string   `json:"key"    binding:"required"`
+	Chords []string `json:"chords" binding:"required"`
+}
+
+// ParallelResponse is the progression rewritten in the parallel key.
+type ParallelResponse struct {
+	Key         string              `json:"key"`
+	ParallelKey string              `json:"parallelKey"` // e.g. "Cm" for "C"
+	Results     []ReharmonizedChord `json:"results"`
+}
+
+// SoloGuideRequest asks which notes to target over each chord of a progression.
+type SoloGuideRequest struct {
+	Key    string   `json:"key"    binding:"required"`
+	Chords []string `json:"chords" binding:"required"`
+}
+
+// SoloGuideChord is the soloing advice for one chord.
+type SoloGuideChord struct {
+	Chord        string   `json:"chord"`
+	Scale        string   `json:"scale"`        // e.g. "D Dorian"
+	Diatonic     bool     `json:"diatonic"`     // false = scale chosen from the chord quality alone
+	ScaleNotes   []string `json:"scaleNotes"`   // from the chord root
+	ChordTones   []string `json:"chordTones"`   // targets to land on
+	PassingTones []string `json:"passingTones"` // scale notes between the targets
+}
+
+// SoloGuideResponse is the per-chord soloing guide for a progression.
+type SoloGuideResponse struct {
+	Key    string           `json:"key"`
+	Chords []SoloGuideChord `json:"chords"`
+}
+
+// ReharmonizeRequest asks to reharmonize a progression in a key.
+type ReharmonizeRequest struct {
+	Key    string   `json:"key"    binding:"required"`
+	Chords []string `json:"chords" binding:"required"`
+}
+
+// ReharmonizeResponse holds the original and reharmonized progressions and a
+// MIDI preview of the reharmonized one.
+type ReharmonizeResponse struct {
+	Key      string   `json:"key"`
+	Style    string   `json:"style"`
+	Original []string `json:"original"`
+	Chords   []string `json:"chords"`
+	Midi     []byte   `json:"midi"` // base64-encoded SMF
+}
+
+// InferKeyRequest asks for the most likely key of a progression.
+type InferKeyRequest struct {
+	Chords []string `json:"chords" binding:"required"`
+}
+
+// KeyCandidate is one key a progression may be in.
+type KeyCandidate struct {
+	Key        string  `json:"key"`        // e.g. "C" or "Am"
+	Diatonic   int     `json:"diatonic"`   // chords that belong to the key
+	Confidence float64 `json:"confidence"` // 0–1; tonic at the start or end counts towards it
+}
+
+// InferKeyResponse lists the best-scoring keys, most likely first.
+type InferKeyResponse struct {
+	Candidates []KeyCandidate `json:"candidates"`
+}
+
+// ChordFormulaResponse describes the interval content of a chord.
+type ChordFormulaResponse struct {
+	Chord     string   `json:"chord"`
+	Quality   string   `json:"quality"`   // suffix after the root, "" for a major triad
+	Semitones []int    `json:"semitones"` // e.g. [0,4,7,11]
+	Degrees   []string `json:"degrees"`   // e.g. ["1","3","5","7"]
+	Formula   string   `json:"formula"`   // degrees joined, e.g. "1 3 5 7"
+	Notes     []string `json:"notes"`     // note names built on the root
+}
+
+// CagedShape is one CAGED shape moved up the neck to play a chord on guitar.
+type CagedShape struct {
+	Shape     string   `json:"shape"`               // "C", "A", "G", "E" or "D"
+	BaseFret  int      `json:"baseFret"`            // lowest fret of the shape; 0 for an open chord
+	BarreFret int      `json:"barreFret,omitempty"` // fret the index finger barres in place of the nut
+	Frets     []string `json:"frets"`               // low E string first, "x" for muted strings
+}
+
+// CagedResponse lists a chord's CAGED shapes, lowest position first.
+type CagedResponse struct {
+	Chord  string       `json:"chord"`
+	Shapes []CagedShape `json:"shapes"`
+}
+
+// TurnaroundResponse is a generated two-bar turnaround and its MIDI rendering.
+type TurnaroundResponse struct {
+	Key    string   `json:"key"`
+	Style  string   `json:"style"`
+	Chords []string `json:"chords"`
+	Midi   []byte   `json:"midi"` // base64-encoded SMF
+}
+
+// ShiftFretsRequest asks to move a chord shape up (or down) the neck.
+type ShiftFretsRequest struct {
+	Frets     []string `json:"frets"     binding:"required"` // e.g. ["0","2","2","1","0","0"]
+	Semitones int      `json:"semitones"`                    // frets to move; negative moves toward the nut
+}
+
+// ShiftFretsResponse is the shifted shape. Strings pushed past the nut or the
+// top of the neck are muted ("x") and listed in Unplayable.
+type ShiftFretsResponse struct {
+	Frets      []string `json:"frets"`
+	Unplayable []int    `json:"unplayable"` // string indices that had to be muted
+}
+
+// DiagramError is one problem found in a submitted chord diagram.
+type DiagramError struct {
+	Chord   string `json:"chord"`
+	Variant int    `json:"variant"` // index into the chord's variants
+	Error   string `json:"error"`
+}
+
+// ValidateDiagramsResponse lists every problem found in a ChordDiagrams
+// payload; Valid is true when there are none.
+type ValidateDiagramsResponse struct {
+	Valid  bool           `json:"valid"`
+	Errors []DiagramError `json:"errors"`
 }